@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/solver"
+)
+
+const (
+	// CandidateDir is the directory of a candidate schedule, numbered from 1.
+	CandidateDir string = "candidate-%d"
+	// CandidateAttempts is the number of seeds tried per candidate to find distinct schedules.
+	CandidateAttempts       int         = 5
+	CandidateDirPermissions os.FileMode = 0700
+)
+
+type candidate struct {
+	seed      int64
+	overrides []pagerduty.Overrides
+	score     solver.Score
+}
+
+// solveCandidates builds distinct schedules, with successive seeds from the
+// given one, writes them in candidate directories and displays them ranked by
+// score.
+func solveCandidates(cfg config.Config, n int, seed int64, newSolver func() *solver.Solver) {
+	candidates := []candidate{}
+
+	for i := int64(0); len(candidates) < n && i < int64(n*CandidateAttempts); i++ {
+		sv := newSolver()
+		// first candidate is the schedule built without candidates
+		sv.SetSeed(seed + i)
+
+		overrides, err := sv.Run()
+		if err != nil {
+			log.Debug().Msgf("no candidate with seed %d: %s", seed+i, err.Error())
+			continue
+		}
+
+		if slices.ContainsFunc(candidates, func(c candidate) bool { return reflect.DeepEqual(c.overrides, overrides) }) {
+			log.Debug().Msgf("candidate with seed %d already found", seed+i)
+			continue
+		}
+
+		candidates = append(candidates, candidate{seed: seed + i, overrides: overrides, score: sv.Score()})
+	}
+
+	if len(candidates) == 0 {
+		panic(errors.New("no candidate schedule found"))
+	}
+
+	for i, c := range candidates {
+		dir := fmt.Sprintf(CandidateDir, i+1)
+		err := os.MkdirAll(dir, CandidateDirPermissions)
+		if err != nil {
+			panic(err)
+		}
+
+		err = writeSchedule(dir, cfg, c.overrides, c.score)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	if len(candidates) < n {
+		log.Info().Msgf("only %d distinct candidate schedules found", len(candidates))
+	}
+
+	displayCandidates(candidates)
+}
+
+func displayCandidates(candidates []candidate) {
+	ranked := make([]int, len(candidates))
+	for i := range ranked {
+		ranked[i] = i
+	}
+	slices.SortStableFunc(ranked, func(a, b int) int {
+		switch {
+		case candidates[a].score.Total < candidates[b].score.Total:
+			return -1
+		case candidates[a].score.Total > candidates[b].score.Total:
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	h := color.New(color.FgHiBlue).Add(color.Bold)
+	line := "+------+--------------+----------------------+--------+--------+--------+------+------+------+"
+	log.Info().Msg("")
+	log.Info().Msg(line)
+	log.Info().Msgf("| %s | %s    | %s | %s  | %s  | %s  | %s   | %s   | %s   |",
+		h.Sprint("Rank"), h.Sprint("Candidate"), h.Sprint("Seed")+strings.Repeat(" ", 16), h.Sprint("Score"), h.Sprint("S var"), h.Sprint("W var"),
+		h.Sprint("BB"), h.Sprint("FB"), h.Sprint("PV"))
+	log.Info().Msg(line)
+
+	for rank, i := range ranked {
+		c := candidates[i]
+		name := fmt.Sprintf(CandidateDir, i+1)
+		log.Info().Msgf("| %4d | %s%s | %20d | %6.2f | %6.2f | %6.2f | %4d | %4d | %4d |",
+			rank+1, name, strings.Repeat(" ", max(0, 12-len(name))), c.seed, c.score.Total, c.score.ShiftsVariance,
+			c.score.WeekendsVariance, c.score.BackToBack, c.score.Fallbacks, c.score.PreferenceViolations)
+	}
+
+	log.Info().Msg(line)
+	log.Info().Msg("")
+}
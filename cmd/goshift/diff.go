@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/schedule"
+)
+
+func diff(args []string) {
+	var token, baseURL, primaryID, secondaryID, dir, configPath, usersPath string
+	var debug bool
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.BoolVar(&debug, "debug", false, "sets log level to debug")
+	fs.StringVar(&token, "token", os.Getenv("PAGERDUTY_TOKEN"), "[optional] pagerduty api token (defaults to $PAGERDUTY_TOKEN)")
+	fs.StringVar(&baseURL, "url", pagerduty.DefaultBaseURL, "[optional] pagerduty api base url")
+	fs.StringVar(&primaryID, "primary-schedule", "", "[optional] pagerduty primary schedule id (when no config file is used)")
+	fs.StringVar(&secondaryID, "secondary-schedule", "", "[optional] pagerduty secondary schedule id (when no config file is used)")
+	fs.StringVar(&configPath, "config", "", "[optional] team config json file path")
+	fs.StringVar(&dir, "dir", ".", "[optional] directory holding layers json files")
+	fs.StringVar(&usersPath, "users", os.Getenv("HOME")+"/Documents/pagerduty-users.json", "[optional] users json file path")
+
+	err := fs.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+
+	setLogLevel(debug)
+
+	if token == "" {
+		panic(errors.New("pagerduty api token is missing"))
+	}
+
+	cfg, err := loadConfig(configPath, primaryID, secondaryID)
+	if err != nil {
+		panic(err)
+	}
+
+	users, err := readUsers(usersPath)
+	if err != nil {
+		panic(err)
+	}
+
+	client := pagerduty.NewClient(baseURL, token)
+
+	for _, layer := range cfg.Layers {
+		if layer.ScheduleID == "" {
+			continue
+		}
+
+		generated, err := readOverrides(filepath.Join(dir, layer.File))
+		if err != nil {
+			panic(err)
+		}
+
+		if len(generated.Overrides) == 0 {
+			continue
+		}
+
+		since := generated.Overrides[0].Start
+		until := generated.Overrides[len(generated.Overrides)-1].End
+		existing, err := client.ListOverrides(context.Background(), layer.ScheduleID, since, until)
+		if err != nil {
+			panic(err)
+		}
+
+		schedule.DisplayDiff(layer.Title()+" on-call shift changes", pagerduty.Diff(users.Resolve(existing), generated))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestDiffCommand(t *testing.T) {
+	alice := pagerduty.AssignedUser{ID: "P1", Name: "Alice", Email: "alice@email.com", Type: "user_reference"}
+	bob := pagerduty.AssignedUser{ID: "P2", Name: "Bob", Email: "bob@email.com", Type: "user_reference"}
+	start := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	override := func(day int, u pagerduty.AssignedUser) pagerduty.Override {
+		d := start.Add(time.Duration(day) * 24 * time.Hour)
+		return pagerduty.Override{Start: d, End: d.Add(24 * time.Hour), User: u}
+	}
+
+	// existing overrides only hold user references
+	existing := pagerduty.Overrides{Overrides: []pagerduty.Override{
+		override(0, pagerduty.AssignedUser{ID: alice.ID}),
+		override(1, pagerduty.AssignedUser{ID: alice.ID}),
+	}}
+	generated := pagerduty.Overrides{Overrides: []pagerduty.Override{
+		override(0, alice),
+		override(1, bob),
+		override(2, bob),
+	}}
+
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		_ = json.NewEncoder(w).Encode(existing)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	writeJSON(t, filepath.Join(dir, "primary.json"), generated)
+	writeJSON(t, filepath.Join(dir, "users.json"), pagerduty.Users{Users: []pagerduty.User{
+		{ID: alice.ID, Name: alice.Name, Email: alice.Email, Type: alice.Type},
+		{ID: bob.ID, Name: bob.Name, Email: bob.Email, Type: bob.Type},
+	}})
+
+	var out bytes.Buffer
+	logger, noColor := log.Logger, color.NoColor
+	log.Logger = log.Output(&out)
+	color.NoColor = true
+	defer func() { log.Logger, color.NoColor = logger, noColor }()
+
+	diff([]string{"-token", "token", "-url", server.URL, "-primary-schedule", "S1", "-dir", dir, "-users", filepath.Join(dir, "users.json")})
+
+	if len(requests) != 1 || requests[0] != "GET /schedules/S1/overrides" {
+		t.Errorf("got requests %v, want the overrides of the primary schedule only", requests)
+	}
+
+	for _, want := range []string{"~ 2024-09-02 Alice -> Bob", "+ 2024-09-03 Bob", "1 added, 0 removed, 1 changed"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output does not contain %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func writeJSON(t *testing.T, path string, v any) {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(path, data, WriteFilePermissions)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func fetchUsers(args []string) {
+	var token, baseURL, scheduleID, teamID, usersPath string
+	var debug bool
+
+	fs := flag.NewFlagSet("fetch-users", flag.ExitOnError)
+	fs.BoolVar(&debug, "debug", false, "sets log level to debug")
+	fs.StringVar(&token, "token", os.Getenv("PAGERDUTY_TOKEN"), "[optional] pagerduty api token (defaults to $PAGERDUTY_TOKEN)")
+	fs.StringVar(&baseURL, "url", pagerduty.DefaultBaseURL, "[optional] pagerduty api base url")
+	fs.StringVar(&scheduleID, "schedule", "", "[optional] pagerduty schedule id to load users from")
+	fs.StringVar(&teamID, "team", "", "[optional] pagerduty team id to load users from its members")
+	fs.StringVar(&usersPath, "users", os.Getenv("HOME")+"/Documents/pagerduty-users.json", "[optional] users json output file path")
+
+	err := fs.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+
+	setLogLevel(debug)
+
+	if token == "" {
+		panic(errors.New("pagerduty api token is missing"))
+	}
+
+	if (scheduleID == "") == (teamID == "") {
+		panic(errors.New("exactly one of pagerduty schedule id or team id is required"))
+	}
+
+	client := pagerduty.NewClient(baseURL, token)
+
+	var users pagerduty.Users
+	if teamID != "" {
+		users, err = client.TeamMembers(context.Background(), teamID)
+	} else {
+		users, err = client.ScheduleUsers(context.Background(), scheduleID)
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	u, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+
+	err = os.WriteFile(usersPath, u, WriteFilePermissions)
+	if err != nil {
+		panic(err)
+	}
+
+	log.Info().Msgf("Successfully downloaded %d users to %s", len(users.Users), usersPath)
+}
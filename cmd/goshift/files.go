@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/solver"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// loadConfig reads the team config file, or defaults to primary and secondary layers.
+func loadConfig(path, primaryID, secondaryID string) (config.Config, error) {
+	if path == "" {
+		return config.Default(primaryID, secondaryID), nil
+	}
+
+	return config.Load(path)
+}
+
+func readUsers(path string) (pagerduty.Users, error) {
+	var users pagerduty.Users
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return users, errors.New("unable to open users file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &users)
+	if err != nil {
+		return users, errors.New("unable to unmarshall users JSON value: " + err.Error())
+	}
+
+	return users, nil
+}
+
+func readOverrides(path string) (pagerduty.Overrides, error) {
+	var overrides pagerduty.Overrides
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return overrides, errors.New("unable to read overrides file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &overrides)
+	if err != nil {
+		return overrides, errors.New("unable to unmarshall overrides JSON value: " + err.Error())
+	}
+
+	return overrides, nil
+}
+
+func readPublished(path string) (map[string][]string, error) {
+	published := make(map[string][]string)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return published, nil
+	}
+	if err != nil {
+		return published, errors.New("unable to read published file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &published)
+	if err != nil {
+		return published, errors.New("unable to unmarshall published JSON value: " + err.Error())
+	}
+
+	return published, nil
+}
+
+func writePublished(path string, published map[string][]string) error {
+	data, err := json.MarshalIndent(published, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, WriteFilePermissions)
+}
+
+// readLedger reads the ledger of previous months, empty when the file does not exist yet.
+func readLedger(path string) (solver.Ledger, error) {
+	ledger := solver.Ledger{}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ledger, nil
+	}
+	if err != nil {
+		return nil, errors.New("unable to read ledger file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &ledger)
+	if err != nil {
+		return nil, errors.New("unable to unmarshall ledger JSON value: " + err.Error())
+	}
+
+	return ledger, nil
+}
+
+func writeLedger(path string, ledger solver.Ledger) error {
+	data, err := json.MarshalIndent(ledger, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, WriteFilePermissions)
+}
+
+// writeSchedule writes the layers overrides and the score of a schedule in a directory.
+func writeSchedule(dir string, cfg config.Config, overrides []pagerduty.Overrides, score solver.Score) error {
+	for i, layer := range cfg.Layers {
+		data, err := json.MarshalIndent(overrides[i], "", "  ")
+		if err != nil {
+			return err
+		}
+
+		err = os.WriteFile(filepath.Join(dir, layer.File), data, WriteFilePermissions)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(score, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, ScoreFile), data, WriteFilePermissions)
+}
+
+// loadHolidays reads public holidays files, given as "path" or "COUNTRY=path".
+// ICS calendars and simple YAML lists are supported.
+func loadHolidays(specs []string) (pagerduty.Holidays, error) {
+	holidays := pagerduty.Holidays{}
+
+	for _, spec := range specs {
+		country, path, ok := strings.Cut(spec, "=")
+		if !ok {
+			country, path = "", spec
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, errors.New("unable to read holidays file " + path + " : " + err.Error())
+		}
+
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".ics":
+			dates, err := utils.ParseICS(data)
+			if err != nil {
+				return nil, errors.New("unable to parse holidays ics file " + path + " : " + err.Error())
+			}
+			holidays.Add(country, dates...)
+		default:
+			h, err := utils.ParseHolidaysYAML(data, country)
+			if err != nil {
+				return nil, errors.New("unable to parse holidays yaml file " + path + " : " + err.Error())
+			}
+			for c, dates := range h {
+				holidays.Add(c, dates...)
+			}
+		}
+	}
+
+	return holidays, nil
+}
+
+// loadPreferences reads the preferred dates and weekdays of users.
+func loadPreferences(path string, users []pagerduty.User, location *time.Location, handover time.Duration) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return errors.New("unable to read preferences file " + path + " : " + err.Error())
+	}
+
+	return utils.ParsePreferences(data, users, location, handover)
+}
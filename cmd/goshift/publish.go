@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// PublishedFile records the ids of the overrides created by goshift, per schedule.
+const PublishedFile string = "goshift-published.json"
+
+func publish(args []string) { //nolint:funlen // todo
+	var token, baseURL, primaryID, secondaryID, dir, configPath string
+	var debug, reconcile bool
+
+	fs := flag.NewFlagSet("publish", flag.ExitOnError)
+	fs.BoolVar(&debug, "debug", false, "sets log level to debug")
+	fs.StringVar(&token, "token", os.Getenv("PAGERDUTY_TOKEN"), "[optional] pagerduty api token (defaults to $PAGERDUTY_TOKEN)")
+	fs.StringVar(&baseURL, "url", pagerduty.DefaultBaseURL, "[optional] pagerduty api base url")
+	fs.StringVar(&primaryID, "primary-schedule", "", "[optional] pagerduty primary schedule id (when no config file is used)")
+	fs.StringVar(&secondaryID, "secondary-schedule", "", "[optional] pagerduty secondary schedule id (when no config file is used)")
+	fs.StringVar(&configPath, "config", "", "[optional] team config json file path")
+	fs.StringVar(&dir, "dir", ".", "[optional] directory holding layers json files")
+	fs.BoolVar(&reconcile, "reconcile", false, "[optional] delete stale overrides previously published by goshift and only create missing ones")
+
+	err := fs.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+
+	setLogLevel(debug)
+
+	if token == "" {
+		panic(errors.New("pagerduty api token is missing"))
+	}
+
+	cfg, err := loadConfig(configPath, primaryID, secondaryID)
+	if err != nil {
+		panic(err)
+	}
+
+	client := pagerduty.NewClient(baseURL, token)
+	ctx := context.Background()
+
+	publishedPath := filepath.Join(dir, PublishedFile)
+	published, err := readPublished(publishedPath)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, layer := range cfg.Layers {
+		if layer.ScheduleID == "" {
+			continue
+		}
+
+		overrides, err := readOverrides(filepath.Join(dir, layer.File))
+		if err != nil {
+			panic(err)
+		}
+
+		if len(overrides.Overrides) == 0 {
+			continue
+		}
+
+		plan := pagerduty.Plan{
+			Create: overrides.Overrides,
+		}
+
+		if reconcile {
+			since := overrides.Overrides[0].Start
+			until := overrides.Overrides[len(overrides.Overrides)-1].End
+			existing, err := client.ListOverrides(ctx, layer.ScheduleID, since, until)
+			if err != nil {
+				panic(err)
+			}
+
+			plan = pagerduty.Reconcile(existing, overrides, published[layer.ScheduleID])
+		}
+
+		for _, o := range plan.Keep {
+			log.Info().Msgf("kept override %s on %s: %s -> %s", o.ID, layer.ScheduleID, o.Start, o.End)
+		}
+
+		for _, o := range plan.Manual {
+			log.Info().Msgf("kept manual override %s on %s: %s -> %s", o.ID, layer.ScheduleID, o.Start, o.End)
+		}
+
+		for _, o := range plan.Skip {
+			log.Info().Msgf("skipped override on %s conflicting with a manual override: %s -> %s", layer.ScheduleID, o.Start, o.End)
+		}
+
+		for _, o := range plan.Delete {
+			err = client.DeleteOverride(ctx, layer.ScheduleID, o.ID)
+			if err != nil {
+				// overrides deleted so far are no longer owned
+				if werr := writePublished(publishedPath, published); werr != nil {
+					panic(werr)
+				}
+				panic(err)
+			}
+			published[layer.ScheduleID] = slices.DeleteFunc(published[layer.ScheduleID], func(id string) bool { return id == o.ID })
+			log.Info().Msgf("deleted override %s on %s: %s -> %s", o.ID, layer.ScheduleID, o.Start, o.End)
+		}
+
+		var created pagerduty.Overrides
+		if len(plan.Create) > 0 {
+			created, err = client.CreateOverrides(ctx, layer.ScheduleID, pagerduty.Overrides{Overrides: plan.Create})
+		}
+		// only overrides the API created are owned, failed ones are created again by the next run
+		for _, o := range created.Overrides {
+			if o.ID == "" {
+				log.Info().Msgf("created override on %s without id, it will not be reconciled: %s -> %s", layer.ScheduleID, o.Start, o.End)
+				continue
+			}
+			published[layer.ScheduleID] = append(published[layer.ScheduleID], o.ID)
+			log.Info().Msgf("created override %s on %s: %s -> %s", o.ID, layer.ScheduleID, o.Start, o.End)
+		}
+
+		// keep track of what has been done, even on partial failure
+		if werr := writePublished(publishedPath, published); werr != nil {
+			panic(werr)
+		}
+
+		if err != nil {
+			panic(err)
+		}
+
+		log.Info().Msgf("Successfully published %s to %s: %d created, %d deleted, %d kept, %d skipped",
+			layer.File, layer.ScheduleID, len(created.Overrides), len(plan.Delete), len(plan.Keep), len(plan.Skip))
+	}
+}
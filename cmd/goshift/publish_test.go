@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// fakeSchedule is a PagerDuty schedule holding overrides, failing to create
+// overrides of the failing users and to delete the undeletable overrides.
+type fakeSchedule struct {
+	overrides   []pagerduty.Override
+	failing     []string
+	undeletable []string
+	created     int
+	deleted     []string
+}
+
+func (f *fakeSchedule) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/schedules/S1/overrides":
+		_ = json.NewEncoder(w).Encode(pagerduty.Overrides{Overrides: f.overrides})
+	case r.Method == http.MethodPost && r.URL.Path == "/schedules/S1/overrides":
+		var in pagerduty.Overrides
+		_ = json.NewDecoder(r.Body).Decode(&in)
+
+		results := []map[string]any{}
+		for _, o := range in.Overrides {
+			if slices.Contains(f.failing, o.User.ID) {
+				results = append(results, map[string]any{"status": http.StatusBadRequest, "errors": []string{"User not found"}})
+				continue
+			}
+
+			f.created++
+			o.ID = fmt.Sprintf("O%d", f.created)
+			f.overrides = append(f.overrides, o)
+			results = append(results, map[string]any{"status": http.StatusCreated, "override": o})
+		}
+		_ = json.NewEncoder(w).Encode(results)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/schedules/S1/overrides/"):
+		id := strings.TrimPrefix(r.URL.Path, "/schedules/S1/overrides/")
+		if slices.Contains(f.undeletable, id) {
+			http.Error(w, `{"error": {"message": "Internal Server Error"}}`, http.StatusInternalServerError)
+			return
+		}
+		f.deleted = append(f.deleted, id)
+		f.overrides = slices.DeleteFunc(f.overrides, func(o pagerduty.Override) bool { return o.ID == id })
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestPublishPartialFailure(t *testing.T) {
+	alice := pagerduty.AssignedUser{ID: "P1", Email: "alice@email.com", Type: "user_reference"}
+	bob := pagerduty.AssignedUser{ID: "P2", Email: "bob@email.com", Type: "user_reference"}
+	start := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	generated := pagerduty.Overrides{Overrides: []pagerduty.Override{
+		{Start: start, End: start.Add(24 * time.Hour), User: alice},
+		{Start: start.Add(24 * time.Hour), End: start.Add(48 * time.Hour), User: bob},
+	}}
+
+	dir := t.TempDir()
+	writeJSON(t, filepath.Join(dir, "primary.json"), generated)
+
+	fake := &fakeSchedule{failing: []string{bob.ID}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	args := []string{"-token", "token", "-url", server.URL, "-primary-schedule", "S1", "-dir", dir, "-reconcile"}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("publish did not fail while an override was not created")
+			}
+		}()
+		publish(args)
+	}()
+
+	published, err := readPublished(filepath.Join(dir, PublishedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published["S1"], []string{"O1"}) {
+		t.Errorf("got published %v after partial failure, want [O1]", published["S1"])
+	}
+
+	// publishing again only creates the missing override
+	fake.failing = nil
+	publish(args)
+
+	if fake.created != 2 || len(fake.deleted) != 0 {
+		t.Errorf("got %d created and %v deleted overrides, want 2 created and none deleted", fake.created, fake.deleted)
+	}
+
+	published, err = readPublished(filepath.Join(dir, PublishedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published["S1"], []string{"O1", "O2"}) {
+		t.Errorf("got published %v, want [O1 O2]", published["S1"])
+	}
+
+	// publishing a third time does nothing
+	publish(args)
+
+	if fake.created != 2 || len(fake.deleted) != 0 {
+		t.Errorf("got %d created and %v deleted overrides on re-run, want nothing done", fake.created, fake.deleted)
+	}
+}
+
+func TestPublishDeleteFailure(t *testing.T) {
+	alice := pagerduty.AssignedUser{ID: "P1", Email: "alice@email.com", Type: "user_reference"}
+	bob := pagerduty.AssignedUser{ID: "P2", Email: "bob@email.com", Type: "user_reference"}
+	start := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+	day := func(i int) time.Time { return start.Add(time.Duration(i) * 24 * time.Hour) }
+
+	generated := pagerduty.Overrides{Overrides: []pagerduty.Override{
+		{Start: day(0), End: day(1), User: alice},
+		{Start: day(1), End: day(2), User: alice},
+	}}
+
+	dir := t.TempDir()
+	writeJSON(t, filepath.Join(dir, "primary.json"), generated)
+	writeJSON(t, filepath.Join(dir, PublishedFile), map[string][]string{"S1": {"O1", "O2"}})
+
+	// both owned overrides are stale, the second one can not be deleted
+	fake := &fakeSchedule{
+		overrides: []pagerduty.Override{
+			{ID: "O1", Start: day(0), End: day(1), User: bob},
+			{ID: "O2", Start: day(1), End: day(2), User: bob},
+		},
+		undeletable: []string{"O2"},
+		created:     2,
+	}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("publish did not fail while an override was not deleted")
+			}
+		}()
+		publish([]string{"-token", "token", "-url", server.URL, "-primary-schedule", "S1", "-dir", dir, "-reconcile"})
+	}()
+
+	published, err := readPublished(filepath.Join(dir, PublishedFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(published["S1"], []string{"O2"}) {
+		t.Errorf("got published %v after delete failure, want [O2]", published["S1"])
+	}
+	if fake.created != 2 {
+		t.Errorf("got %d created overrides, want none created after delete failure", fake.created-2)
+	}
+}
@@ -0,0 +1,519 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/schedule"
+	"github.com/jtbonhomme/goshift/internal/solver"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// ScoreFile holds the score of the generated schedule.
+const ScoreFile = "score.json"
+
+func solve(args []string) { //nolint:funlen // todo
+	var err error
+	var csvPath, usersPath, newbiesPath, previousDir, token, baseURL, primaryID, secondaryID, configPath, engine, ledgerPath, constraintsPath, preferencesPath, pinsPath string
+	var debug bool
+	var improve time.Duration
+	var candidates, improveIterations int
+	var seed int64
+	var lastUsers, holidaysPaths arrayFlags
+
+	fs := flag.NewFlagSet("solve", flag.ExitOnError)
+	fs.BoolVar(&debug, "debug", false, "sets log level to debug")
+	fs.StringVar(&csvPath, "csv", "", "[mandatory] framadate csv file path")
+	fs.StringVar(&usersPath, "users", os.Getenv("HOME")+"/Documents/pagerduty-users.json", "[optional] users json file path")
+	fs.StringVar(&newbiesPath, "newbies", os.Getenv("HOME")+"/Documents/pagerduty-newbies.json", "[optional] newbies json file path")
+	fs.Var(&lastUsers, "last", "[optional] last users emails of previous schedule")
+	fs.StringVar(&configPath, "config", "", "[optional] team config json file path")
+	fs.Var(&holidaysPaths, "holidays", "[optional] public holidays ics or yaml file path, optionally prefixed by a country (FR=fr.ics)")
+	fs.StringVar(&engine, "engine", solver.Greedy, "[optional] solver engine: greedy or exact")
+	fs.Int64Var(&seed, "seed", 0, "[optional] random seed breaking ties between users (defaults to a random seed)")
+	fs.IntVar(&candidates, "candidates", 1, "[optional] number of candidate schedules to generate and rank")
+	fs.DurationVar(&improve, "improve", 0, "[optional] local search improvement time limit (e.g. 10s)")
+	fs.IntVar(&improveIterations, "improve-iterations", 0, fmt.Sprintf("[optional] local search improvement iterations (defaults to %d when -improve is set)", solver.DefaultImproveIterations))
+	fs.StringVar(&constraintsPath, "constraints", "", "[optional] constraints json file path, declaring pairs of users never or preferably together")
+	fs.StringVar(&pinsPath, "pins", "", "[optional] pins json file path, assigning users to layers on given dates before solving")
+	fs.StringVar(&preferencesPath, "preferences", "", "[optional] preferences json file path, declaring the dates and weekdays users would rather be on call")
+	fs.StringVar(&ledgerPath, "ledger", "", "[optional] ledger json file path, balancing shifts across months")
+	fs.StringVar(&previousDir, "previous", "", "[optional] directory holding previous schedule layers json files")
+	fs.StringVar(&token, "token", os.Getenv("PAGERDUTY_TOKEN"), "[optional] pagerduty api token (defaults to $PAGERDUTY_TOKEN)")
+	fs.StringVar(&baseURL, "url", pagerduty.DefaultBaseURL, "[optional] pagerduty api base url")
+	fs.StringVar(&primaryID, "primary-schedule", "", "[optional] pagerduty primary schedule id to read previous overrides from (when no config file is used)")
+	fs.StringVar(&secondaryID, "secondary-schedule", "", "[optional] pagerduty secondary schedule id to read previous overrides from (when no config file is used)")
+
+	err = fs.Parse(args)
+	if err != nil {
+		panic(err)
+	}
+
+	setLogLevel(debug)
+
+	// ties between users are broken at random, the seed allows to build the same schedule again
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		seed = time.Now().UnixNano()
+	}
+
+	if csvPath == "" {
+		panic(errors.New("framadate csv file is missing"))
+	}
+
+	cfg, err := loadConfig(configPath, primaryID, secondaryID)
+	if err != nil {
+		panic(err)
+	}
+
+	users, err := readUsers(usersPath)
+	if err != nil {
+		panic(err)
+	}
+	log.Info().Msg("Successfully opened users.json")
+
+	newbiesJSON, err := os.Open(newbiesPath)
+	if err != nil {
+		panic(errors.New("unable to open newbies file " + newbiesPath + " : " + err.Error()))
+	}
+
+	log.Info().Msg("Successfully opened newbies.json")
+	defer newbiesJSON.Close()
+	newbiesValue, err := io.ReadAll(newbiesJSON)
+	if err != nil {
+		panic(errors.New("unable to read json file : " + err.Error()))
+	}
+
+	var newbies []string
+	err = json.Unmarshal(newbiesValue, &newbies)
+	if err != nil {
+		panic(errors.New("unable to unmarshall newbies JSON value: " + err.Error()))
+	}
+
+	f, err := os.Open(csvPath)
+	if err != nil {
+		panic(errors.New("unable to open csv file " + csvPath + " : " + err.Error()))
+	}
+	defer f.Close()
+
+	csvReader := csv.NewReader(f)
+	data, err := csvReader.ReadAll()
+	if err != nil {
+		panic(errors.New("unable to read csv file : " + err.Error()))
+	}
+
+	location, err := cfg.Location()
+	if err != nil {
+		panic(err)
+	}
+
+	handover, err := cfg.HandoverTime()
+	if err != nil {
+		panic(err)
+	}
+
+	input := utils.ParseFramadateCSV(data, location, handover)
+
+	err = cfg.ApplyUsers(input.Users, users)
+	if err != nil {
+		panic(err)
+	}
+
+	if preferencesPath != "" {
+		err = loadPreferences(preferencesPath, input.Users, location, handover)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	holidays, err := loadHolidays(holidaysPaths)
+	if err != nil {
+		panic(err)
+	}
+
+	unavailablitiesStats := input.UnavailablitiesStats(cfg.Weekend)
+
+	// last users of the previous schedule are on-call the day before the schedule starts
+	lastDay := input.ScheduleStart.Add(-utils.OneDay)
+	history := map[string][]time.Time{}
+	if len(lastUsers) == 0 {
+		previous, err := previousOverrides(cfg, previousDir, token, baseURL, input.ScheduleStart)
+		if err != nil {
+			panic(err)
+		}
+
+		lastUsers = users.OnCallEmails(lastDay, previous...)
+		log.Info().Msgf("Last users of previous schedule: %v", []string(lastUsers))
+
+		// anti-fatigue rules apply across the month boundary
+		for i := 1; i <= cfg.Rules.Lookback(); i++ {
+			d := input.ScheduleStart.Add(-time.Duration(i) * utils.OneDay)
+			for _, email := range users.OnCallEmails(d, previous...) {
+				if email != "" {
+					history[email] = append(history[email], d)
+				}
+			}
+		}
+	} else {
+		for _, email := range lastUsers {
+			if email != "" {
+				history[email] = append(history[email], lastDay)
+			}
+		}
+	}
+
+	constraints := config.Constraints{}
+	if constraintsPath != "" {
+		constraints, err = config.LoadConstraints(constraintsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	pins := config.Pins{}
+	if pinsPath != "" {
+		pins, err = config.LoadPins(pinsPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	ledger := solver.Ledger{}
+	if ledgerPath != "" {
+		ledger, err = readLedger(ledgerPath)
+		if err != nil {
+			panic(err)
+		}
+	}
+
+	// the local search stops after a number of iterations, so that a seed builds the same schedule again
+	if improve > 0 && improveIterations == 0 {
+		improveIterations = solver.DefaultImproveIterations
+	}
+
+	newSolver := func() *solver.Solver {
+		sv := solver.New(cfg, input, users, newbies, []string(lastUsers))
+		sv.SetHolidays(holidays)
+		err := sv.SetEngine(engine)
+		if err != nil {
+			panic(err)
+		}
+		sv.SetImprove(improveIterations, improve)
+		sv.SetSeed(seed)
+		sv.SetLedger(ledger)
+		sv.SetHistory(history)
+		sv.SetConstraints(constraints)
+		err = sv.SetPins(pins)
+		if err != nil {
+			panic(err)
+		}
+
+		return sv
+	}
+
+	sv := newSolver()
+	log.Info().Msgf("Seed: %d", seed)
+	displayCoverage(cfg, sv.Coverage())
+
+	if candidates > 1 {
+		solveCandidates(cfg, candidates, seed, newSolver)
+		if ledgerPath != "" {
+			log.Info().Msgf("Ledger %s not updated, run again with the seed of the chosen candidate to update it", ledgerPath)
+		}
+		return
+	}
+
+	overrides, err := sv.Run()
+	if err != nil {
+		var infeasible *solver.InfeasibleError
+		if errors.As(err, &infeasible) {
+			displayRejections(infeasible)
+		}
+		panic(err)
+	}
+
+	err = writeSchedule(".", cfg, overrides, sv.Score())
+	if err != nil {
+		panic(err)
+	}
+
+	if ledgerPath != "" {
+		ledger.Record(sv.Month(), sv.Stats, sv.WeekendStats, sv.HolidayStats)
+		err = writeLedger(ledgerPath, ledger)
+		if err != nil {
+			panic(err)
+		}
+		log.Info().Msgf("Ledger %s updated for %s", ledgerPath, sv.Month())
+	}
+
+	log.Info().Msg("")
+
+	for i, layer := range cfg.Layers {
+		if len(cfg.Windows) > 0 {
+			schedule.DisplaySlots(layer.Title()+" on-call shift", overrides[i], slots(cfg.Windows), cfg.Weekend, holidays, cfg.Country)
+			continue
+		}
+
+		schedule.DisplayCalendar(layer.Title()+" on-call shift", overrides[i], cfg.Weekend, holidays, cfg.Country)
+	}
+
+	log.Info().Msg("")
+
+	h := color.New(color.FgHiBlue).Add(color.Bold)
+	border := "+%s+----+----+----+----+----+"
+	header := "| %s                                                        |  %s |  %s |  %s |   %s | %s |"
+	headers := []any{h.Sprint("Email"), h.Sprint("S"), h.Sprint("W"), h.Sprint("H"), h.Sprint("u"), h.Sprint("v")}
+	if ledgerPath != "" {
+		border += "----+----+----+"
+		header += " %s | %s | %s |"
+		headers = append(headers, h.Sprint("LS"), h.Sprint("LW"), h.Sprint("LH"))
+	}
+	ifNeeded := slices.ContainsFunc(input.Users, func(u pagerduty.User) bool { return len(u.IfNeeded) > 0 })
+	if ifNeeded {
+		border += "----+"
+		header += " %s |"
+		headers = append(headers, h.Sprint("IN"))
+	}
+	shadows := slices.ContainsFunc(cfg.Layers, func(l config.Layer) bool { return l.Shadow })
+	if shadows {
+		border += "----+"
+		header += " %s |"
+		headers = append(headers, h.Sprint("Sh"))
+	}
+	preferences := sv.Preferences()
+	preferring := slices.ContainsFunc(input.Users, func(u pagerduty.User) bool {
+		return len(u.Preferred) > 0 || len(u.PreferredWeekdays) > 0
+	})
+	if preferring {
+		border += "-------+"
+		header += " %s  |"
+		headers = append(headers, h.Sprint("Pref"))
+	}
+	quotas := hasQuotas(input.Users)
+	if quotas {
+		border += "------+-----+-----+-----+"
+		header += " %s  | %s | %s | %s |"
+		headers = append(headers, h.Sprint("Cap"), h.Sprint("Min"), h.Sprint("Max"), h.Sprint("MxW"))
+	}
+
+	log.Info().Msgf(border, strings.Repeat("-", LineLength))
+	log.Info().Msgf(header, headers...)
+	log.Info().Msgf(border, strings.Repeat("-", LineLength))
+
+	for _, user := range input.Users {
+		line := fmt.Sprintf("| %s %s| %2d | %2d | %2d | %2d | %2d |",
+			user.Email, strings.Repeat(" ", LineLengthMinusWhitespaces-len(user.Email)),
+			sv.Stats[user.Email], sv.WeekendStats[user.Email], sv.HolidayStats[user.Email],
+			unavailablitiesStats.Weekdays[user.Email], unavailablitiesStats.Weekends[user.Email])
+		if ledgerPath != "" {
+			debt := sv.Debt[user.Email]
+			line += fmt.Sprintf(" %+2d | %+2d | %+2d |", debt.Shifts, debt.Weekends, debt.Holidays)
+		}
+		if ifNeeded {
+			line += fmt.Sprintf(" %2d |", sv.IfNeededStats[user.Email])
+		}
+		if shadows {
+			line += fmt.Sprintf(" %2d |", sv.ShadowStats[user.Email])
+		}
+		if preferring {
+			// preferred shifts satisfied out of the preferred ones
+			p := preferences[user.Email]
+			line += fmt.Sprintf(" %2d/%-2d |", p.Satisfied, p.Preferred)
+		}
+		if quotas {
+			line += quotaColumns(user, sv.Stats[user.Email])
+		}
+		log.Info().Msg(line)
+	}
+	log.Info().Msgf(border, strings.Repeat("-", LineLength))
+	log.Info().Msg("")
+
+	score := sv.Score()
+	log.Info().Msgf("Score: %s (shifts variance %.2f, week-ends variance %.2f, back-to-back %d, fallbacks %d, preference violations %d)",
+		h.Sprintf("%.2f", score.Total), score.ShiftsVariance, score.WeekendsVariance, score.BackToBack, score.Fallbacks, score.PreferenceViolations)
+	log.Info().Msg("")
+
+	displayPairings(sv.PairingViolations())
+}
+
+// displayPairings lists the days prefer-together pairs of users were apart.
+func displayPairings(violations []solver.PairingViolation) {
+	if len(violations) == 0 {
+		return
+	}
+
+	log.Info().Msg("Prefer-together pairs not on call together:")
+	for _, v := range violations {
+		days := []string{}
+		for _, d := range v.Days {
+			days = append(days, d.Format(time.DateOnly))
+		}
+		log.Info().Msgf("  %s and %s: %d days (%s)", v.Users[0], v.Users[1], len(v.Days), strings.Join(days, ", "))
+	}
+	log.Info().Msg("")
+}
+
+// hasQuotas tells whether a user has a capacity or quotas set.
+func hasQuotas(users []pagerduty.User) bool {
+	for _, user := range users {
+		if user.Capacity > 0 || user.MinShifts > 0 || user.MaxShifts > 0 || user.MaxWeekends > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// quotaColumns returns the capacity and quotas of a user, a minimum not met
+// being highlighted.
+func quotaColumns(user pagerduty.User, shifts int) string {
+	limit := func(n int) string {
+		if n == 0 {
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+
+	capacity := 100
+	if user.Capacity > 0 {
+		capacity = int(math.Round(user.Capacity * 100)) //nolint:gomnd // percent
+	}
+
+	minimum := fmt.Sprintf("%3s", limit(user.MinShifts))
+	if user.MinShifts > 0 && shifts < user.MinShifts {
+		minimum = color.New(color.FgHiRed).Sprint(minimum)
+	}
+
+	return fmt.Sprintf(" %3d%% | %s | %3s | %3s |", capacity, minimum, limit(user.MaxShifts), limit(user.MaxWeekends))
+}
+
+// displayCoverage summarizes the days with too few available users, before solving.
+func displayCoverage(cfg config.Config, coverage []solver.Coverage) {
+	under, tight := 0, 0
+	for _, c := range coverage {
+		switch {
+		case c.UnderCovered():
+			under++
+		case c.Tight():
+			tight++
+		}
+	}
+
+	if under == 0 && tight == 0 {
+		return
+	}
+
+	log.Info().Msgf("Coverage: %d under-covered days, %d tight days", under, tight)
+
+	for _, c := range coverage {
+		if !c.UnderCovered() && !c.Tight() {
+			continue
+		}
+
+		layers := []string{}
+		for i, layer := range cfg.Layers[:len(c.Layers)] {
+			layers = append(layers, fmt.Sprintf("%s %d", layer.Name, c.Layers[i]))
+		}
+
+		line := fmt.Sprintf("%s %s: %d available users for %d needed (%s)",
+			c.Day.Format(time.DateOnly), c.Day.Weekday().String()[:3], c.Available, c.Needed, strings.Join(layers, ", "))
+		if c.UnderCovered() {
+			log.Info().Msg(color.New(color.FgHiRed).Sprint("⚠️  " + line))
+		} else {
+			log.Info().Msg(color.New(color.FgHiYellow).Sprint("   " + line))
+		}
+	}
+
+	log.Info().Msg("")
+}
+
+// displayRejections lists why each user was rejected on the day no user could be selected.
+func displayRejections(infeasible *solver.InfeasibleError) {
+	log.Info().Msgf("No user for %s on %s:", infeasible.Layer, infeasible.Day.Format(time.DateOnly))
+
+	for _, r := range infeasible.Rejections {
+		log.Info().Msgf("  %s %s%s", r.Email, strings.Repeat(" ", max(1, LineLengthMinusWhitespaces-len(r.Email))), r.Reason)
+	}
+
+	log.Info().Msg("")
+}
+
+// slots returns the follow-the-sun windows to display.
+func slots(windows []config.Window) []schedule.Slot {
+	slots := []schedule.Slot{}
+	for _, w := range windows {
+		start, _, _ := w.Times()
+		slots = append(slots, schedule.Slot{Name: w.Name, Start: start})
+	}
+
+	return slots
+}
+
+// previousOverrides reads the overrides of the previous schedule, either from
+// a previous run directory or from the PagerDuty schedules. Shadow layers are
+// not on call and skipped.
+func previousOverrides(cfg config.Config, dir, token, baseURL string, start time.Time) ([]pagerduty.Overrides, error) {
+	previous := []pagerduty.Overrides{}
+
+	if dir != "" {
+		for _, layer := range cfg.Layers {
+			if layer.Shadow {
+				continue
+			}
+
+			overrides, err := readOverrides(filepath.Join(dir, layer.File))
+			if err != nil {
+				return nil, err
+			}
+			previous = append(previous, overrides)
+		}
+
+		return previous, nil
+	}
+
+	if token == "" {
+		return previous, nil
+	}
+
+	client := pagerduty.NewClient(baseURL, token)
+	for _, layer := range cfg.Layers {
+		if layer.Shadow {
+			continue
+		}
+
+		// layers stay in order, a layer without schedule having no previous overrides
+		if layer.ScheduleID == "" {
+			previous = append(previous, pagerduty.Overrides{})
+			continue
+		}
+
+		// a week is enough to catch multi-days overrides covering the last day,
+		// rules may look further back
+		lookback := time.Duration(cfg.Rules.Lookback()) * utils.OneDay
+		overrides, err := client.ListOverrides(context.Background(), layer.ScheduleID, start.Add(-lookback), start)
+		if err != nil {
+			return nil, err
+		}
+		previous = append(previous, overrides)
+	}
+
+	return previous, nil
+}
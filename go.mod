@@ -3,9 +3,12 @@ module github.com/jtbonhomme/goshift
 go 1.21.3
 
 require (
-	github.com/fatih/color v1.16.0 // indirect
+	github.com/fatih/color v1.16.0
+	github.com/rs/zerolog v1.32.0
+)
+
+require (
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
-	github.com/rs/zerolog v1.32.0 // indirect
 	golang.org/x/sys v0.17.0 // indirect
 )
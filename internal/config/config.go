@@ -0,0 +1,435 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+const (
+	DefaultTimezone string = "Europe/Paris"
+	DefaultHandover string = "09:00"
+)
+
+// Shift lengths.
+const (
+	// Daily shifts are one day long, week-end days included.
+	Daily string = "daily"
+	// WeekendBundled shifts are one day long, except week-ends shaped by the week-end definition.
+	WeekendBundled string = "weekend-bundled"
+	// Weekly shifts are one week long, starting on the handover day.
+	Weekly string = "weekly"
+	// Custom shifts are a custom number of days long.
+	Custom string = "custom"
+)
+
+// Config describes how the on-call team is organized.
+type Config struct {
+	// Timezone is the schedule time zone, handover times are expressed in it.
+	Timezone string `json:"timezone,omitempty"`
+	Handover string `json:"handover,omitempty"`
+	// Country is the default country of users for public holidays.
+	Country string            `json:"country,omitempty"`
+	Layers  []Layer           `json:"layers"`
+	Weekend pagerduty.Weekend `json:"weekend"`
+	Shift   Shift             `json:"shift"`
+	Windows []Window          `json:"windows,omitempty"`
+	Weights Weights           `json:"weights"`
+	Rules   Rules             `json:"rules"`
+	Users   map[string]User   `json:"users,omitempty"`
+}
+
+// Window is a time window of the day (e.g. 09:00-21:00), filled by users of
+// its regions, to split days into follow-the-sun shifts.
+type Window struct {
+	Name  string `json:"name"`
+	Start string `json:"start"`
+	End   string `json:"end"`
+	// Regions restricts the window to users of these regions.
+	Regions []string `json:"regions,omitempty"`
+}
+
+// Times returns the window start and end times of day. The window ends the
+// next day when its end is not after its start.
+func (w Window) Times() (start, end time.Duration, err error) {
+	start, err = pagerduty.ParseTimeOfDay(w.Start)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	end, err = pagerduty.ParseTimeOfDay(w.End)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// validateWindows checks that windows cover the day in time order, each
+// window starting when the previous one ends, without overlap nor gap.
+func validateWindows(windows []Window) error {
+	var length time.Duration
+
+	for i, w := range windows {
+		start, end, err := w.Times()
+		if err != nil {
+			return errors.New("invalid window " + w.Name + " : " + err.Error())
+		}
+
+		next := windows[(i+1)%len(windows)]
+		nextStart, _, err := next.Times()
+		if err != nil {
+			return errors.New("invalid window " + next.Name + " : " + err.Error())
+		}
+
+		if end != nextStart {
+			return fmt.Errorf("window %s ends at %s but window %s starts at %s, windows must cover the day in time order", w.Name, w.End, next.Name, next.Start)
+		}
+
+		length += (end - start + 24*time.Hour) % (24 * time.Hour)
+	}
+
+	// windows ending where they started go round the day more than once
+	if len(windows) > 1 && length != 24*time.Hour {
+		return errors.New("windows must cover the day once, without overlap")
+	}
+
+	return nil
+}
+
+// Weights weigh the terms of the schedule score, the lower the better.
+type Weights struct {
+	ShiftsVariance       float64 `json:"shifts_variance"`
+	WeekendsVariance     float64 `json:"weekends_variance"`
+	BackToBack           float64 `json:"back_to_back"`
+	Fallbacks            float64 `json:"fallbacks"`
+	PreferenceViolations float64 `json:"preference_violations"`
+}
+
+// DefaultWeights returns the default score weights.
+func DefaultWeights() Weights {
+	return Weights{
+		ShiftsVariance:       1,
+		WeekendsVariance:     2,
+		BackToBack:           5,
+		Fallbacks:            3,
+		PreferenceViolations: 1,
+	}
+}
+
+// Rules are anti-fatigue rules applied to every user, across layers and
+// across the previous schedule. Zero values disable them.
+type Rules struct {
+	// MinRestDays is the number of days off between two shifts of a user.
+	MinRestDays int `json:"min_rest_days,omitempty"`
+	// MaxShiftsPerWeek is the maximum number of shifts of a user in any
+	// rolling 7 days.
+	MaxShiftsPerWeek int `json:"max_shifts_per_week,omitempty"`
+	// WeekendRest forbids a workday shift of a user with at most one day off
+	// before or after one of its week-end shifts (e.g. Thursday or Tuesday
+	// around a Saturday-Sunday shift), shifts right before or after being
+	// already forbidden.
+	WeekendRest bool `json:"weekend_rest,omitempty"`
+}
+
+// Lookback returns the number of days of the previous schedule the rules
+// apply to, at least a week.
+func (r Rules) Lookback() int {
+	return max(7, r.MinRestDays+1) //nolint:gomnd // days of a week
+}
+
+func (r Rules) validate() error {
+	if r.MinRestDays < 0 || r.MaxShiftsPerWeek < 0 {
+		return errors.New("rules limits can not be negative")
+	}
+
+	return nil
+}
+
+// Shift defines the length of shifts.
+type Shift struct {
+	Length string `json:"length,omitempty"`
+	// Days is the number of days of custom shifts.
+	Days int `json:"days,omitempty"`
+	// HandoverDay is the first day of weekly shifts.
+	HandoverDay string `json:"handover_day,omitempty"`
+}
+
+// HandoverWeekday returns the first day of weekly shifts, Monday by default.
+func (s Shift) HandoverWeekday() time.Weekday {
+	d, err := pagerduty.ParseWeekday(s.HandoverDay)
+	if err != nil {
+		return time.Monday
+	}
+
+	return d
+}
+
+func (s Shift) validate() error {
+	switch s.Length {
+	case "", Daily, WeekendBundled, Weekly:
+	case Custom:
+		if s.Days <= 0 {
+			return errors.New("custom shifts require a positive number of days")
+		}
+	default:
+		return errors.New("unknown shift length " + s.Length)
+	}
+
+	if s.HandoverDay != "" {
+		if _, err := pagerduty.ParseWeekday(s.HandoverDay); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// User holds per user settings, indexed by email.
+type User struct {
+	// TimeZone overrides the PagerDuty user time zone.
+	TimeZone string `json:"time_zone,omitempty"`
+	// Weekend lists the user local week-end days, when they differ from the schedule ones.
+	Weekend []string `json:"weekend,omitempty"`
+	// Country overrides the default country for public holidays.
+	Country string `json:"country,omitempty"`
+	// Region is used to select users of follow-the-sun windows.
+	Region string `json:"region,omitempty"`
+	// Capacity is the share of a full-time on-call load (e.g. 0.8 for a
+	// part-time user), above 0 and up to 1, 1 when not set.
+	Capacity *float64 `json:"capacity,omitempty"`
+	// MinShifts, MaxShifts and MaxWeekends bound the shift days and week-end
+	// shifts of the user in a month, when set.
+	MinShifts   int `json:"min_shifts,omitempty"`
+	MaxShifts   int `json:"max_shifts,omitempty"`
+	MaxWeekends int `json:"max_weekends,omitempty"`
+	// Senior users can back up any newbie on mentor layers.
+	Senior bool `json:"senior,omitempty"`
+	// Mentors lists the designated mentors of a newbie.
+	Mentors []string `json:"mentors,omitempty"`
+}
+
+func (u User) validate() error {
+	// a user without on-call load is left out of the layers rather than given a zero capacity
+	if u.Capacity != nil && (*u.Capacity <= 0 || *u.Capacity > 1) {
+		return errors.New("capacity must be above 0 and up to 1")
+	}
+
+	if u.MinShifts < 0 || u.MaxShifts < 0 || u.MaxWeekends < 0 {
+		return errors.New("quotas can not be negative")
+	}
+
+	if u.MaxShifts > 0 && u.MinShifts > u.MaxShifts {
+		return errors.New("min shifts can not exceed max shifts")
+	}
+
+	return nil
+}
+
+// Layer is an on-call schedule layer (e.g. primary, secondary, manager) with
+// its own eligibility rule, output file and PagerDuty schedule.
+type Layer struct {
+	Name       string `json:"name"`
+	File       string `json:"file,omitempty"`
+	ScheduleID string `json:"schedule_id,omitempty"`
+	// ExcludeNewbies prevents newbies from being selected on this layer.
+	ExcludeNewbies bool `json:"exclude_newbies,omitempty"`
+	// Roles restricts the layer to users with one of these team roles.
+	Roles []string `json:"roles,omitempty"`
+	// Emails restricts the layer to these users.
+	Emails []string `json:"emails,omitempty"`
+	// Mentor layers back up newbies on call on other layers: their user must
+	// be a mentor of the newbie or a senior user.
+	Mentor bool `json:"mentor,omitempty"`
+	// Shadow layers are filled by newbies observing the shift alongside
+	// one of their mentors or a senior user, without counting in stats. They
+	// come after the other layers.
+	Shadow bool `json:"shadow,omitempty"`
+}
+
+func (l Layer) Title() string {
+	if l.Name == "" {
+		return ""
+	}
+
+	return strings.ToUpper(l.Name[:1]) + l.Name[1:]
+}
+
+// Default returns the historical primary and secondary layers configuration.
+func Default(primaryID, secondaryID string) Config {
+	return Config{
+		Timezone: DefaultTimezone,
+		Handover: DefaultHandover,
+		Layers: []Layer{
+			{
+				Name:       "primary",
+				File:       "primary.json",
+				ScheduleID: primaryID,
+			},
+			{
+				Name:           "secondary",
+				File:           "secondary.json",
+				ScheduleID:     secondaryID,
+				ExcludeNewbies: true,
+			},
+		},
+		Weekend: pagerduty.DefaultWeekend(),
+		Shift: Shift{
+			Length: WeekendBundled,
+		},
+		Weights: DefaultWeights(),
+	}
+}
+
+func Load(path string) (Config, error) {
+	cfg := Config{
+		Timezone: DefaultTimezone,
+		Handover: DefaultHandover,
+		Weekend:  pagerduty.DefaultWeekend(),
+		Shift: Shift{
+			Length: WeekendBundled,
+		},
+		Weights: DefaultWeights(),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, errors.New("unable to read config file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &cfg)
+	if err != nil {
+		return cfg, errors.New("unable to unmarshall config JSON value: " + err.Error())
+	}
+
+	if len(cfg.Layers) == 0 {
+		return cfg, errors.New("no layer defined in config file " + path)
+	}
+
+	_, err = cfg.Location()
+	if err != nil {
+		return cfg, err
+	}
+
+	_, err = cfg.HandoverTime()
+	if err != nil {
+		return cfg, err
+	}
+
+	err = cfg.Shift.validate()
+	if err != nil {
+		return cfg, err
+	}
+
+	err = cfg.Rules.validate()
+	if err != nil {
+		return cfg, err
+	}
+
+	err = validateWindows(cfg.Windows)
+	if err != nil {
+		return cfg, err
+	}
+
+	for email, u := range cfg.Users {
+		if err = u.validate(); err != nil {
+			return cfg, errors.New("invalid user " + email + " : " + err.Error())
+		}
+	}
+
+	for i, l := range cfg.Layers {
+		if l.Name == "" {
+			return cfg, errors.New("missing layer name in config file " + path)
+		}
+
+		if i == 0 && l.Shadow {
+			return cfg, errors.New("no on-call layer defined in config file " + path)
+		}
+
+		if i > 0 && cfg.Layers[i-1].Shadow && !l.Shadow {
+			return cfg, errors.New("shadow layers must come after the other layers in config file " + path)
+		}
+
+		if l.Shadow && l.Mentor {
+			return cfg, errors.New("shadow layer " + l.Name + " can not be a mentor layer")
+		}
+
+		if l.File == "" {
+			cfg.Layers[i].File = l.Name + ".json"
+		}
+	}
+
+	return cfg, nil
+}
+
+func (cfg Config) Location() (*time.Location, error) {
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %w", cfg.Timezone, err)
+	}
+
+	return loc, nil
+}
+
+// HandoverTime returns the handover time of day as a duration since midnight.
+func (cfg Config) HandoverTime() (time.Duration, error) {
+	return pagerduty.ParseTimeOfDay(cfg.Handover)
+}
+
+// ApplyUsers sets the time zone, country, region, local week-end days, capacity,
+// quotas and seniority of users, from the config file or else from the
+// PagerDuty users.
+func (cfg Config) ApplyUsers(users []pagerduty.User, known pagerduty.Users) error {
+	for i, user := range users {
+		for _, k := range known.Users {
+			if k.Email == user.Email {
+				users[i].TimeZone = k.TimeZone
+			}
+		}
+
+		settings := cfg.Users[user.Email]
+		if settings.TimeZone != "" {
+			users[i].TimeZone = settings.TimeZone
+		}
+
+		if users[i].TimeZone != "" {
+			loc, err := time.LoadLocation(users[i].TimeZone)
+			if err != nil {
+				return fmt.Errorf("invalid time zone %s for user %s: %w", users[i].TimeZone, user.Email, err)
+			}
+			users[i].Location = loc
+		}
+
+		users[i].Country = cfg.Country
+		if settings.Country != "" {
+			users[i].Country = settings.Country
+		}
+
+		users[i].Region = settings.Region
+		users[i].Capacity = 0
+		if settings.Capacity != nil {
+			users[i].Capacity = *settings.Capacity
+		}
+		users[i].MinShifts = settings.MinShifts
+		users[i].MaxShifts = settings.MaxShifts
+		users[i].MaxWeekends = settings.MaxWeekends
+		users[i].Senior = settings.Senior
+		users[i].Mentors = settings.Mentors
+
+		users[i].Weekend = nil
+		for _, day := range settings.Weekend {
+			weekday, err := pagerduty.ParseWeekday(day)
+			if err != nil {
+				return fmt.Errorf("invalid week-end for user %s: %w", user.Email, err)
+			}
+			users[i].Weekend = append(users[i].Weekend, weekday)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidateWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []Window
+		valid   bool
+	}{
+		{name: "no window", valid: true},
+		{name: "whole day", windows: []Window{{Name: "all", Start: "09:00", End: "09:00"}}, valid: true},
+		{
+			name:    "two windows",
+			windows: []Window{{Name: "emea", Start: "08:00", End: "20:00"}, {Name: "amer", Start: "20:00", End: "08:00"}},
+			valid:   true,
+		},
+		{
+			name: "three windows",
+			windows: []Window{
+				{Name: "apac", Start: "00:00", End: "08:00"},
+				{Name: "emea", Start: "08:00", End: "16:00"},
+				{Name: "amer", Start: "16:00", End: "00:00"},
+			},
+			valid: true,
+		},
+		{name: "part of the day", windows: []Window{{Name: "day", Start: "09:00", End: "21:00"}}},
+		{
+			name:    "gap",
+			windows: []Window{{Name: "emea", Start: "08:00", End: "18:00"}, {Name: "amer", Start: "20:00", End: "08:00"}},
+		},
+		{
+			name:    "overlap",
+			windows: []Window{{Name: "emea", Start: "08:00", End: "20:00"}, {Name: "amer", Start: "18:00", End: "08:00"}},
+		},
+		{
+			name: "out of time order",
+			windows: []Window{
+				{Name: "emea", Start: "08:00", End: "16:00"},
+				{Name: "apac", Start: "00:00", End: "08:00"},
+				{Name: "amer", Start: "16:00", End: "00:00"},
+			},
+		},
+		{
+			name:    "around the day twice",
+			windows: []Window{{Name: "emea", Start: "08:00", End: "08:00"}, {Name: "amer", Start: "08:00", End: "08:00"}},
+		},
+		{name: "invalid time", windows: []Window{{Name: "emea", Start: "8h", End: "8h"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateWindows(tt.windows)
+			if (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestValidateUser(t *testing.T) {
+	zero, half, one, more := 0.0, 0.5, 1.0, 1.5
+	negative := -0.5
+
+	tests := []struct {
+		name  string
+		user  User
+		valid bool
+	}{
+		{name: "no setting", valid: true},
+		{name: "part-time", user: User{Capacity: &half}, valid: true},
+		{name: "full-time", user: User{Capacity: &one}, valid: true},
+		{name: "zero capacity", user: User{Capacity: &zero}},
+		{name: "negative capacity", user: User{Capacity: &negative}},
+		{name: "capacity above full-time", user: User{Capacity: &more}},
+		{name: "quotas", user: User{MinShifts: 2, MaxShifts: 6, MaxWeekends: 1}, valid: true},
+		{name: "min shifts without max shifts", user: User{MinShifts: 2}, valid: true},
+		{name: "min shifts above max shifts", user: User{MinShifts: 6, MaxShifts: 2}},
+		{name: "negative min shifts", user: User{MinShifts: -1}},
+		{name: "negative max shifts", user: User{MaxShifts: -1}},
+		{name: "negative max week-ends", user: User{MaxWeekends: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.user.validate()
+			if (err == nil) != tt.valid {
+				t.Errorf("got error %v, want valid %t", err, tt.valid)
+			}
+		})
+	}
+}
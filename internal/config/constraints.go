@@ -0,0 +1,67 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// Pair rules.
+const (
+	// NeverTogether users are never on call the same day.
+	NeverTogether string = "never-together"
+	// PreferTogether users are preferably on call the same days.
+	PreferTogether string = "prefer-together"
+)
+
+// Constraints declare rules between users, on any layer.
+type Constraints struct {
+	Pairs []Pair `json:"pairs"`
+}
+
+// Pair is a rule between two users.
+type Pair struct {
+	Users []string `json:"users"`
+	Rule  string   `json:"rule"`
+}
+
+// WithRule returns the pairs of users following a rule.
+func (c Constraints) WithRule(rule string) [][2]string {
+	pairs := [][2]string{}
+
+	for _, p := range c.Pairs {
+		if p.Rule == rule {
+			pairs = append(pairs, [2]string{p.Users[0], p.Users[1]})
+		}
+	}
+
+	return pairs
+}
+
+func LoadConstraints(path string) (Constraints, error) {
+	var c Constraints
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, errors.New("unable to read constraints file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &c)
+	if err != nil {
+		return c, errors.New("unable to unmarshall constraints JSON value: " + err.Error())
+	}
+
+	for _, p := range c.Pairs {
+		if len(p.Users) != 2 || p.Users[0] == p.Users[1] {
+			return c, errors.New("a pair needs two distinct users in constraints file " + path)
+		}
+
+		switch p.Rule {
+		case NeverTogether, PreferTogether:
+		default:
+			return c, errors.New("unknown pair rule " + p.Rule + " in constraints file " + path)
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// Pins are agreed-upon assignments, set before solving and never changed.
+type Pins struct {
+	Pins []Pin `json:"pins"`
+}
+
+// Pin assigns a user to a layer the day of a date (2006-01-02). With
+// follow-the-sun windows, the window must be given.
+type Pin struct {
+	Date   string `json:"date"`
+	Layer  string `json:"layer"`
+	Window string `json:"window,omitempty"`
+	User   string `json:"user"`
+}
+
+func LoadPins(path string) (Pins, error) {
+	var p Pins
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, errors.New("unable to read pins file " + path + " : " + err.Error())
+	}
+
+	err = json.Unmarshal(data, &p)
+	if err != nil {
+		return p, errors.New("unable to unmarshall pins JSON value: " + err.Error())
+	}
+
+	for _, pin := range p.Pins {
+		if _, err := time.Parse(time.DateOnly, pin.Date); err != nil {
+			return p, errors.New("invalid pin date " + pin.Date + " in pins file " + path)
+		}
+
+		if pin.Layer == "" || pin.User == "" {
+			return p, errors.New("a pin needs a layer and a user in pins file " + path)
+		}
+	}
+
+	return p, nil
+}
@@ -0,0 +1,346 @@
+package pagerduty
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	DefaultBaseURL    string        = "https://api.pagerduty.com"
+	DefaultPageLimit  int           = 100
+	DefaultMaxRetries int           = 5
+	DefaultRetryDelay time.Duration = time.Second
+	DefaultTimeout    time.Duration = 30 * time.Second
+)
+
+// Client is a minimal PagerDuty REST API client authenticated with an API token.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	PageLimit  int
+	MaxRetries int
+	RetryDelay time.Duration
+}
+
+// APIError is the error object returned by the PagerDuty API.
+type APIError struct {
+	StatusCode int      `json:"-"`
+	Code       int      `json:"code"`
+	Message    string   `json:"message"`
+	Errors     []string `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pagerduty api error %d", e.StatusCode)
+	if e.Code != 0 {
+		msg += fmt.Sprintf(" (code %d)", e.Code)
+	}
+
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	if len(e.Errors) > 0 {
+		msg += " [" + strings.Join(e.Errors, ", ") + "]"
+	}
+
+	return msg
+}
+
+type pagination struct {
+	Offset int  `json:"offset"`
+	Limit  int  `json:"limit"`
+	More   bool `json:"more"`
+}
+
+type overrideResult struct {
+	Status   int      `json:"status"`
+	Override Override `json:"override"`
+	Errors   []string `json:"errors"`
+}
+
+func NewClient(baseURL, token string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		PageLimit:  DefaultPageLimit,
+		MaxRetries: DefaultMaxRetries,
+		RetryDelay: DefaultRetryDelay,
+	}
+}
+
+// ScheduleUsers downloads all users of a schedule, following pagination.
+func (c *Client) ScheduleUsers(ctx context.Context, scheduleID string) (Users, error) {
+	users := Users{
+		Users: []User{},
+	}
+
+	for offset := 0; ; {
+		var page struct {
+			pagination
+			Users []User `json:"users"`
+		}
+
+		query := url.Values{}
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(c.PageLimit))
+
+		err := c.do(ctx, http.MethodGet, "/schedules/"+url.PathEscape(scheduleID)+"/users", query, nil, &page)
+		if err != nil {
+			return Users{}, fmt.Errorf("unable to list users of schedule %s: %w", scheduleID, err)
+		}
+
+		users.Users = append(users.Users, page.Users...)
+		if !page.More || len(page.Users) == 0 {
+			break
+		}
+		offset += len(page.Users)
+	}
+
+	return users, nil
+}
+
+// TeamMembers downloads all members of a team, following pagination, and keeps
+// their team role (observer, responder or manager) on each user.
+func (c *Client) TeamMembers(ctx context.Context, teamID string) (Users, error) {
+	roles := make(map[string]string)
+
+	for offset := 0; ; {
+		var page struct {
+			pagination
+			Members []struct {
+				User AssignedUser `json:"user"`
+				Role string       `json:"role"`
+			} `json:"members"`
+		}
+
+		query := url.Values{}
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(c.PageLimit))
+
+		err := c.do(ctx, http.MethodGet, "/teams/"+url.PathEscape(teamID)+"/members", query, nil, &page)
+		if err != nil {
+			return Users{}, fmt.Errorf("unable to list members of team %s: %w", teamID, err)
+		}
+
+		for _, m := range page.Members {
+			roles[m.User.ID] = m.Role
+		}
+		if !page.More || len(page.Members) == 0 {
+			break
+		}
+		offset += len(page.Members)
+	}
+
+	// members only hold user references, full users (with emails) are listed separately
+	users := Users{
+		Users: []User{},
+	}
+
+	for offset := 0; ; {
+		var page struct {
+			pagination
+			Users []User `json:"users"`
+		}
+
+		query := url.Values{}
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(c.PageLimit))
+		query.Add("team_ids[]", teamID)
+
+		err := c.do(ctx, http.MethodGet, "/users", query, nil, &page)
+		if err != nil {
+			return Users{}, fmt.Errorf("unable to list users of team %s: %w", teamID, err)
+		}
+
+		for _, u := range page.Users {
+			role, ok := roles[u.ID]
+			if !ok {
+				continue
+			}
+			u.TeamRole = role
+			users.Users = append(users.Users, u)
+		}
+		if !page.More || len(page.Users) == 0 {
+			break
+		}
+		offset += len(page.Users)
+	}
+
+	return users, nil
+}
+
+// ListOverrides downloads all overrides of a schedule between since and until, following pagination.
+func (c *Client) ListOverrides(ctx context.Context, scheduleID string, since, until time.Time) (Overrides, error) {
+	overrides := Overrides{
+		Overrides: []Override{},
+	}
+
+	for offset := 0; ; {
+		var page struct {
+			pagination
+			Overrides []Override `json:"overrides"`
+		}
+
+		query := url.Values{}
+		query.Set("offset", strconv.Itoa(offset))
+		query.Set("limit", strconv.Itoa(c.PageLimit))
+		query.Set("since", since.Format(time.RFC3339))
+		query.Set("until", until.Format(time.RFC3339))
+
+		err := c.do(ctx, http.MethodGet, "/schedules/"+url.PathEscape(scheduleID)+"/overrides", query, nil, &page)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("unable to list overrides of schedule %s: %w", scheduleID, err)
+		}
+
+		overrides.Overrides = append(overrides.Overrides, page.Overrides...)
+		if !page.More || len(page.Overrides) == 0 {
+			break
+		}
+		offset += len(page.Overrides)
+	}
+
+	return overrides, nil
+}
+
+// CreateOverrides posts overrides to a schedule and returns the created overrides.
+func (c *Client) CreateOverrides(ctx context.Context, scheduleID string, overrides Overrides) (Overrides, error) {
+	var results []overrideResult
+
+	err := c.do(ctx, http.MethodPost, "/schedules/"+url.PathEscape(scheduleID)+"/overrides", nil, overrides, &results)
+	if err != nil {
+		return Overrides{}, fmt.Errorf("unable to create overrides on schedule %s: %w", scheduleID, err)
+	}
+
+	created := Overrides{
+		Overrides: []Override{},
+	}
+
+	var failures []string
+	for i, r := range results {
+		if r.Status != http.StatusCreated {
+			failures = append(failures, fmt.Sprintf("override #%d: status %d %s", i, r.Status, strings.Join(r.Errors, ", ")))
+			continue
+		}
+		created.Overrides = append(created.Overrides, r.Override)
+	}
+
+	if len(failures) > 0 {
+		return created, fmt.Errorf("unable to create %d overrides on schedule %s: %s",
+			len(failures), scheduleID, strings.Join(failures, "; "))
+	}
+
+	return created, nil
+}
+
+// DeleteOverride removes an override from a schedule.
+func (c *Client) DeleteOverride(ctx context.Context, scheduleID, overrideID string) error {
+	err := c.do(ctx, http.MethodDelete, "/schedules/"+url.PathEscape(scheduleID)+"/overrides/"+url.PathEscape(overrideID), nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("unable to delete override %s of schedule %s: %w", overrideID, scheduleID, err)
+	}
+
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, in, out any) error {
+	var body []byte
+	var err error
+
+	if in != nil {
+		body, err = json.Marshal(in)
+		if err != nil {
+			return err
+		}
+	}
+
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.pagerduty+json;version=2")
+		req.Header.Set("Authorization", "Token token="+c.Token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.HTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.MaxRetries {
+			delay := c.retryDelay(resp.Header.Get("Retry-After"), attempt)
+			log.Debug().Msgf("rate limited on %s %s, retrying in %s", method, path, delay)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(delay):
+			}
+			continue
+		}
+
+		if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+			return decodeError(resp.StatusCode, data)
+		}
+
+		if out == nil || len(data) == 0 {
+			return nil
+		}
+
+		return json.Unmarshal(data, out)
+	}
+}
+
+func (c *Client) retryDelay(retryAfter string, attempt int) time.Duration {
+	if s, err := strconv.Atoi(retryAfter); err == nil && s >= 0 {
+		return time.Duration(s) * time.Second
+	}
+
+	return c.RetryDelay << attempt
+}
+
+func decodeError(status int, data []byte) error {
+	var e struct {
+		Error APIError `json:"error"`
+	}
+
+	apiErr := &APIError{StatusCode: status}
+	if err := json.Unmarshal(data, &e); err == nil {
+		apiErr.Code = e.Error.Code
+		apiErr.Message = e.Error.Message
+		apiErr.Errors = e.Error.Errors
+	}
+
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(status)
+	}
+
+	return apiErr
+}
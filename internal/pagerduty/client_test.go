@@ -0,0 +1,203 @@
+package pagerduty
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestClient returns a client of a fake PagerDuty API, retrying without delay.
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	c := NewClient(server.URL, "token")
+	c.RetryDelay = time.Millisecond
+
+	return c
+}
+
+func TestScheduleUsersPagination(t *testing.T) {
+	all := []User{{ID: "P1", Email: "a@email.com"}, {ID: "P2", Email: "b@email.com"}, {ID: "P3", Email: "c@email.com"}}
+	offsets := []string{}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/schedules/S1/users" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Token token=token" {
+			t.Errorf("unexpected authorization header %q", got)
+		}
+
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+
+		end := min(offset+limit, len(all))
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"users":  all[offset:end],
+			"offset": offset,
+			"limit":  limit,
+			"more":   end < len(all),
+		})
+	})
+	c.PageLimit = 2
+
+	users, err := c.ScheduleUsers(context.Background(), "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users.Users) != len(all) {
+		t.Fatalf("got %d users, want %d", len(users.Users), len(all))
+	}
+	for i, u := range users.Users {
+		if u.ID != all[i].ID {
+			t.Errorf("user %d: got %s, want %s", i, u.ID, all[i].ID)
+		}
+	}
+
+	if strings.Join(offsets, ",") != "0,2" {
+		t.Errorf("got offsets %v, want [0 2]", offsets)
+	}
+}
+
+func TestRetryOnRateLimit(t *testing.T) {
+	calls := 0
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"users": [], "more": false}`))
+	})
+
+	_, err := c.ScheduleUsers(context.Background(), "S1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if calls != 3 {
+		t.Errorf("got %d calls, want 3", calls)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	calls := 0
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	c.MaxRetries = 2
+
+	_, err := c.ScheduleUsers(context.Background(), "S1")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("got error %v, want a %d api error", err, http.StatusTooManyRequests)
+	}
+
+	if calls != c.MaxRetries+1 {
+		t.Errorf("got %d calls, want %d", calls, c.MaxRetries+1)
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	c := NewClient("", "token")
+	c.RetryDelay = time.Second
+
+	tests := []struct {
+		retryAfter string
+		attempt    int
+		want       time.Duration
+	}{
+		{retryAfter: "3", attempt: 0, want: 3 * time.Second},
+		{retryAfter: "0", attempt: 2, want: 0},
+		{retryAfter: "", attempt: 0, want: time.Second},
+		{retryAfter: "", attempt: 2, want: 4 * time.Second},
+		{retryAfter: "soon", attempt: 1, want: 2 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := c.retryDelay(tt.retryAfter, tt.attempt); got != tt.want {
+			t.Errorf("retryDelay(%q, %d) = %s, want %s", tt.retryAfter, tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeError(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"code": 2001, "message": "Invalid Input Provided", "errors": ["Offset must be positive"]}}`))
+	})
+
+	_, err := c.ScheduleUsers(context.Background(), "S1")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("got error %v, want an api error", err)
+	}
+
+	want := APIError{StatusCode: http.StatusBadRequest, Code: 2001, Message: "Invalid Input Provided"}
+	if apiErr.StatusCode != want.StatusCode || apiErr.Code != want.Code || apiErr.Message != want.Message ||
+		len(apiErr.Errors) != 1 || apiErr.Errors[0] != "Offset must be positive" {
+		t.Errorf("got %+v, want %+v", *apiErr, want)
+	}
+}
+
+func TestDecodeErrorWithoutBody(t *testing.T) {
+	err := decodeError(http.StatusForbidden, []byte("forbidden"))
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != http.StatusText(http.StatusForbidden) {
+		t.Errorf("got %v, want the status text as message", err)
+	}
+}
+
+func TestCreateOverridesPartialFailure(t *testing.T) {
+	start := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	overrides := Overrides{Overrides: []Override{
+		{Start: start, End: start.Add(day), User: AssignedUser{ID: "P1"}},
+		{Start: start.Add(day), End: start.Add(2 * day), User: AssignedUser{ID: "P2"}},
+	}}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/schedules/S1/overrides" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		var in Overrides
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil || len(in.Overrides) != 2 {
+			t.Errorf("unexpected body: %v", err)
+		}
+
+		created := in.Overrides[0]
+		created.ID = "O1"
+		_ = json.NewEncoder(w).Encode([]overrideResult{
+			{Status: http.StatusCreated, Override: created},
+			{Status: http.StatusBadRequest, Errors: []string{"User not found"}},
+		})
+	})
+
+	created, err := c.CreateOverrides(context.Background(), "S1", overrides)
+	if err == nil || !strings.Contains(err.Error(), "User not found") {
+		t.Errorf("got error %v, want the failed override to be reported", err)
+	}
+
+	if len(created.Overrides) != 1 || created.Overrides[0].ID != "O1" {
+		t.Errorf("got %+v, want the created override only", created.Overrides)
+	}
+}
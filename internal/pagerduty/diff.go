@@ -0,0 +1,148 @@
+package pagerduty
+
+import (
+	"sort"
+	"time"
+)
+
+const (
+	dayFormat  string        = "2006-01-02"
+	timeFormat string        = "15:04"
+	oneDay     time.Duration = 24 * time.Hour
+)
+
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change describes the difference between the existing and the generated
+// override of a day, or of a window of the day, followed by its start time,
+// when the day is split into follow-the-sun windows.
+type Change struct {
+	Day       string       `json:"day"`
+	Kind      ChangeKind   `json:"kind"`
+	Existing  AssignedUser `json:"existing,omitempty"`
+	Generated AssignedUser `json:"generated,omitempty"`
+}
+
+// Slots expands overrides into the user on call per day and per start time
+// of day, days and times being expressed in the given location: follow-the-sun
+// overrides start several times a day.
+func (overrides Overrides) Slots(loc *time.Location) map[string]map[string]AssignedUser {
+	days := make(map[string]map[string]AssignedUser)
+
+	for _, o := range overrides.Overrides {
+		for t := o.Start.In(loc); t.Before(o.End); t = t.Add(oneDay) {
+			day := t.Format(dayFormat)
+			if days[day] == nil {
+				days[day] = make(map[string]AssignedUser)
+			}
+			days[day][t.Format(timeFormat)] = o.User
+		}
+	}
+
+	return days
+}
+
+// Diff lists added, removed and changed days between existing and generated
+// overrides. Days covered by a single override on both sides are compared as
+// a whole, whatever their start times, and the other ones window by window.
+func Diff(existing, generated Overrides) []Change {
+	changes := []Change{}
+	if len(generated.Overrides) == 0 && len(existing.Overrides) == 0 {
+		return changes
+	}
+
+	loc := time.Local
+	if len(generated.Overrides) > 0 {
+		loc = generated.Overrides[0].Start.Location()
+	}
+
+	e := existing.Slots(loc)
+	g := generated.Slots(loc)
+
+	// existing overrides may start before or end after the generated ones
+	var first, last string
+	for day := range g {
+		if first == "" || day < first {
+			first = day
+		}
+		if day > last {
+			last = day
+		}
+	}
+
+	for day := range g {
+		changes = append(changes, diffDay(day, e[day], g[day])...)
+	}
+
+	for day := range e {
+		if day < first || day > last {
+			continue
+		}
+
+		if _, ok := g[day]; !ok {
+			changes = append(changes, diffDay(day, e[day], nil)...)
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Day < changes[j].Day
+	})
+
+	return changes
+}
+
+// diffDay lists the changes of a day between its existing and generated
+// users per start time.
+func diffDay(day string, existing, generated map[string]AssignedUser) []Change {
+	changes := []Change{}
+
+	// a day with a single override on both sides is compared as a whole
+	key := func(t string) string { return day + " " + t }
+	if len(existing) <= 1 && len(generated) <= 1 {
+		key = func(string) string { return day }
+		existing, generated = atDayStart(existing), atDayStart(generated)
+	}
+
+	for t, gu := range generated {
+		eu, ok := existing[t]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Day: key(t), Kind: Added, Generated: gu})
+		case !sameUser(eu, gu):
+			changes = append(changes, Change{Day: key(t), Kind: Changed, Existing: eu, Generated: gu})
+		}
+	}
+
+	for t, eu := range existing {
+		if _, ok := generated[t]; !ok {
+			changes = append(changes, Change{Day: key(t), Kind: Removed, Existing: eu})
+		}
+	}
+
+	return changes
+}
+
+// atDayStart keys the user of a day covered by a single override by an empty
+// start time.
+func atDayStart(users map[string]AssignedUser) map[string]AssignedUser {
+	day := make(map[string]AssignedUser, len(users))
+	for _, u := range users {
+		day[""] = u
+	}
+
+	return day
+}
+
+func sameUser(a, b AssignedUser) bool {
+	if a.ID != "" && b.ID != "" {
+		return a.ID == b.ID
+	}
+
+	return a.Email == b.Email
+}
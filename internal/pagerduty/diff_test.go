@@ -0,0 +1,192 @@
+package pagerduty
+
+import (
+	"testing"
+	"time"
+)
+
+// nobody skips a day of daily overrides.
+var nobody = AssignedUser{}
+
+// daily returns one day long overrides from September 1st 2024, one per user.
+func daily(users ...AssignedUser) Overrides {
+	overrides := Overrides{Overrides: []Override{}}
+	start := time.Date(2024, 9, 1, 9, 0, 0, 0, time.UTC)
+
+	for i, u := range users {
+		d := start.Add(time.Duration(i) * oneDay)
+		if u == nobody {
+			continue
+		}
+		overrides.Overrides = append(overrides.Overrides, Override{Start: d, End: d.Add(oneDay), User: u})
+	}
+
+	return overrides
+}
+
+func TestDiff(t *testing.T) {
+	alice := AssignedUser{ID: "P1", Name: "Alice", Email: "alice@email.com"}
+	bob := AssignedUser{ID: "P2", Name: "Bob", Email: "bob@email.com"}
+
+	tests := []struct {
+		name      string
+		existing  Overrides
+		generated Overrides
+		want      []Change
+	}{
+		{
+			name:      "no change",
+			existing:  daily(alice, bob),
+			generated: daily(alice, bob),
+			want:      []Change{},
+		},
+		{
+			name:      "added days",
+			existing:  daily(alice),
+			generated: daily(alice, bob),
+			want:      []Change{{Day: "2024-09-02", Kind: Added, Generated: bob}},
+		},
+		{
+			name:      "removed days",
+			existing:  daily(alice, bob, alice),
+			generated: daily(alice, nobody, alice),
+			want:      []Change{{Day: "2024-09-02", Kind: Removed, Existing: bob}},
+		},
+		{
+			name:      "changed days",
+			existing:  daily(alice, bob),
+			generated: daily(bob, bob),
+			want:      []Change{{Day: "2024-09-01", Kind: Changed, Existing: alice, Generated: bob}},
+		},
+		{
+			name:      "existing days out of the generated ones",
+			existing:  daily(alice, bob, alice, bob),
+			generated: daily(alice, bob),
+			want:      []Change{},
+		},
+		{
+			name:      "nothing generated nor existing",
+			existing:  Overrides{},
+			generated: Overrides{},
+			want:      []Change{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.existing, tt.generated)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSameUser(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b AssignedUser
+		want bool
+	}{
+		{name: "same id", a: AssignedUser{ID: "P1"}, b: AssignedUser{ID: "P1", Email: "alice@email.com"}, want: true},
+		{name: "different ids", a: AssignedUser{ID: "P1", Email: "alice@email.com"}, b: AssignedUser{ID: "P2", Email: "alice@email.com"}},
+		{name: "same email without id", a: AssignedUser{Email: "alice@email.com"}, b: AssignedUser{ID: "P1", Email: "alice@email.com"}, want: true},
+		{name: "different emails without id", a: AssignedUser{Email: "alice@email.com"}, b: AssignedUser{ID: "P1", Email: "bob@email.com"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameUser(tt.a, tt.b); got != tt.want {
+				t.Errorf("sameUser(%+v, %+v) = %t, want %t", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// windowed returns overrides of two twelve hours windows a day from September
+// 1st 2024, starting at 08:00 and 20:00, one per user.
+func windowed(users ...AssignedUser) Overrides {
+	overrides := Overrides{Overrides: []Override{}}
+	start := time.Date(2024, 9, 1, 8, 0, 0, 0, time.UTC)
+
+	for i, u := range users {
+		w := start.Add(time.Duration(i) * oneDay / 2)
+		if u == nobody {
+			continue
+		}
+		overrides.Overrides = append(overrides.Overrides, Override{Start: w, End: w.Add(oneDay / 2), User: u})
+	}
+
+	return overrides
+}
+
+func TestDiffWindows(t *testing.T) {
+	alice := AssignedUser{ID: "P1", Name: "Alice", Email: "alice@email.com"}
+	bob := AssignedUser{ID: "P2", Name: "Bob", Email: "bob@email.com"}
+
+	tests := []struct {
+		name      string
+		existing  Overrides
+		generated Overrides
+		want      []Change
+	}{
+		{
+			name:      "no change",
+			existing:  windowed(alice, bob, alice, bob),
+			generated: windowed(alice, bob, alice, bob),
+			want:      []Change{},
+		},
+		{
+			name:      "second window changed",
+			existing:  windowed(alice, bob, alice, bob),
+			generated: windowed(alice, bob, alice, alice),
+			want:      []Change{{Day: "2024-09-02 20:00", Kind: Changed, Existing: bob, Generated: alice}},
+		},
+		{
+			name:      "first window added",
+			existing:  windowed(alice, bob, nobody, bob),
+			generated: windowed(alice, bob, alice, bob),
+			want:      []Change{{Day: "2024-09-02 08:00", Kind: Added, Generated: alice}},
+		},
+		{
+			name:      "windows swapped",
+			existing:  windowed(alice, bob),
+			generated: windowed(bob, alice),
+			want: []Change{
+				{Day: "2024-09-01 08:00", Kind: Changed, Existing: alice, Generated: bob},
+				{Day: "2024-09-01 20:00", Kind: Changed, Existing: bob, Generated: alice},
+			},
+		},
+		{
+			name:      "day split into windows",
+			existing:  daily(alice),
+			generated: windowed(bob, alice),
+			want: []Change{
+				{Day: "2024-09-01 08:00", Kind: Added, Generated: bob},
+				{Day: "2024-09-01 09:00", Kind: Removed, Existing: alice},
+				{Day: "2024-09-01 20:00", Kind: Added, Generated: alice},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.existing, tt.generated)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("change %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
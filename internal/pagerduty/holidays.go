@@ -0,0 +1,24 @@
+package pagerduty
+
+import (
+	"slices"
+	"time"
+)
+
+// Holidays lists public holidays dates per country. Holidays of the empty
+// country apply to every user.
+type Holidays map[string][]string
+
+func (h Holidays) Add(country string, dates ...string) {
+	for _, date := range dates {
+		if !slices.Contains(h[country], date) {
+			h[country] = append(h[country], date)
+		}
+	}
+}
+
+// IsHoliday tells whether the day starting at d is a public holiday in the country.
+func (h Holidays) IsHoliday(country string, d time.Time) bool {
+	date := d.Format(dayFormat)
+	return slices.Contains(h[""], date) || (country != "" && slices.Contains(h[country], date))
+}
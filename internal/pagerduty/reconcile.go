@@ -0,0 +1,77 @@
+package pagerduty
+
+import (
+	"slices"
+)
+
+// Plan lists the API actions needed to turn the existing overrides of a
+// schedule into the generated ones.
+type Plan struct {
+	// Create lists generated overrides missing in the schedule.
+	Create []Override
+	// Delete lists stale overrides previously created by goshift.
+	Delete []Override
+	// Keep lists existing overrides identical to generated ones.
+	Keep []Override
+	// Manual lists manual overrides preventing generated ones to be created.
+	Manual []Override
+	// Skip lists generated overrides conflicting with manual ones.
+	Skip []Override
+}
+
+// Reconcile computes the plan to publish generated overrides over existing ones.
+// Existing overrides whose id is in owned were created by goshift and may be
+// deleted, others are manual overrides and are always kept.
+func Reconcile(existing, generated Overrides, owned []string) Plan {
+	plan := Plan{}
+	satisfied := make([]bool, len(generated.Overrides))
+
+	var manual []Override
+	for _, e := range existing.Overrides {
+		i := slices.IndexFunc(generated.Overrides, func(g Override) bool {
+			return sameOverride(e, g)
+		})
+		if i >= 0 && !satisfied[i] {
+			// identical override is already there, whoever created it
+			satisfied[i] = true
+			plan.Keep = append(plan.Keep, e)
+			continue
+		}
+
+		if slices.Contains(owned, e.ID) {
+			plan.Delete = append(plan.Delete, e)
+			continue
+		}
+
+		manual = append(manual, e)
+	}
+
+	for i, g := range generated.Overrides {
+		if satisfied[i] {
+			continue
+		}
+
+		j := slices.IndexFunc(manual, func(m Override) bool {
+			return overlap(m, g)
+		})
+		if j >= 0 {
+			if !slices.ContainsFunc(plan.Manual, func(m Override) bool { return m.ID == manual[j].ID }) {
+				plan.Manual = append(plan.Manual, manual[j])
+			}
+			plan.Skip = append(plan.Skip, g)
+			continue
+		}
+
+		plan.Create = append(plan.Create, g)
+	}
+
+	return plan
+}
+
+func sameOverride(a, b Override) bool {
+	return a.Start.Equal(b.Start) && a.End.Equal(b.End) && sameUser(a.User, b.User)
+}
+
+func overlap(a, b Override) bool {
+	return a.Start.Before(b.End) && b.Start.Before(a.End)
+}
@@ -0,0 +1,100 @@
+package pagerduty
+
+import (
+	"testing"
+)
+
+// withID returns an override with an id, as listed by the API.
+func withID(o Override, id string) Override {
+	o.ID = id
+	return o
+}
+
+func ids(overrides []Override) []string {
+	ids := []string{}
+	for _, o := range overrides {
+		ids = append(ids, o.ID)
+	}
+
+	return ids
+}
+
+func TestReconcile(t *testing.T) {
+	alice := AssignedUser{ID: "P1", Email: "alice@email.com"}
+	bob := AssignedUser{ID: "P2", Email: "bob@email.com"}
+	carol := AssignedUser{ID: "P3", Email: "carol@email.com"}
+
+	generated := daily(alice, bob, alice)
+	g := generated.Overrides
+
+	tests := []struct {
+		name     string
+		existing []Override
+		owned    []string
+		// ids of planned overrides, generated ones having no id
+		create, delete, keep, manual, skip []string
+	}{
+		{
+			name:   "first publish",
+			create: []string{"", "", ""},
+		},
+		{
+			name:     "re-run after a successful publish",
+			existing: []Override{withID(g[0], "O1"), withID(g[1], "O2"), withID(g[2], "O3")},
+			owned:    []string{"O1", "O2", "O3"},
+			keep:     []string{"O1", "O2", "O3"},
+		},
+		{
+			name:     "stale owned overrides are deleted",
+			existing: []Override{withID(g[0], "O1"), withID(daily(nobody, carol).Overrides[0], "O2")},
+			owned:    []string{"O1", "O2"},
+			create:   []string{"", ""},
+			delete:   []string{"O2"},
+			keep:     []string{"O1"},
+		},
+		{
+			name:     "manual overrides are kept",
+			existing: []Override{withID(daily(nobody, carol).Overrides[0], "M1")},
+			owned:    []string{"O1"},
+			create:   []string{"", ""},
+			manual:   []string{"M1"},
+			skip:     []string{""},
+		},
+		{
+			name:     "manual overrides out of the generated ones are not deleted",
+			existing: []Override{withID(daily(nobody, nobody, nobody, carol).Overrides[0], "M1")},
+			owned:    []string{"O1"},
+			create:   []string{"", "", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := Reconcile(Overrides{Overrides: tt.existing}, generated, tt.owned)
+
+			for _, c := range []struct {
+				name string
+				got  []Override
+				want []string
+			}{
+				{"create", plan.Create, tt.create},
+				{"delete", plan.Delete, tt.delete},
+				{"keep", plan.Keep, tt.keep},
+				{"manual", plan.Manual, tt.manual},
+				{"skip", plan.Skip, tt.skip},
+			} {
+				got := ids(c.got)
+				if len(got) != len(c.want) {
+					t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+					continue
+				}
+				for i := range got {
+					if got[i] != c.want[i] {
+						t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+						break
+					}
+				}
+			}
+		})
+	}
+}
@@ -21,6 +21,7 @@ type Users struct {
 // Override provides the start, end, user, and timezone of the override to work
 // with the PagerDuty API.
 type Override struct {
+	ID    string       `json:"id,omitempty"`
 	Start time.Time    `json:"start"`
 	End   time.Time    `json:"end"`
 	User  AssignedUser `json:"user"`
@@ -30,3 +31,14 @@ type Override struct {
 type Overrides struct {
 	Overrides []Override `json:"overrides"`
 }
+
+// At returns the override covering t, if any.
+func (overrides Overrides) At(t time.Time) (Override, bool) {
+	for _, o := range overrides.Overrides {
+		if !t.Before(o.Start) && t.Before(o.End) {
+			return o, true
+		}
+	}
+
+	return Override{}, false
+}
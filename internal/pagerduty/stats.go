@@ -1,13 +1,11 @@
 package pagerduty
 
-import "time"
-
 type Unavalabilities struct {
 	Weekdays map[string]int
 	Weekends map[string]int
 }
 
-func (input *Input) UnavailablitiesStats() *Unavalabilities {
+func (input *Input) UnavailablitiesStats(weekend Weekend) *Unavalabilities {
 	unavailablitiesStats := &Unavalabilities{
 		Weekdays: make(map[string]int),
 		Weekends: make(map[string]int),
@@ -15,10 +13,10 @@ func (input *Input) UnavailablitiesStats() *Unavalabilities {
 
 	for _, user := range input.Users {
 		for _, d := range user.Unavailable {
-			if d.Weekday().String() != time.Saturday.String() {
+			if !user.IsWeekend(d, weekend) {
 				n := unavailablitiesStats.Weekdays[user.Email]
 				unavailablitiesStats.Weekdays[user.Email] = n + 1
-			} else if d.Weekday().String() == time.Saturday.String() {
+			} else {
 				n := unavailablitiesStats.Weekends[user.Email]
 				unavailablitiesStats.Weekends[user.Email] = n + 1
 			}
@@ -4,15 +4,91 @@ import (
 	"fmt"
 	"slices"
 	"time"
+
+	"github.com/rs/zerolog/log"
 )
 
-// User have a name, id, type, unavailable dates, and preferences.
+// User have a name, id, type, team role, time zone, country, region, local week-end days, capacity, quotas, seniority, unavailable and if needed dates, and preferences.
 type User struct {
-	Name        string      `json:"name,omitempty"`
-	Email       string      `json:"email,omitempty"`
-	ID          string      `json:"id,omitempty"`
-	Type        string      `json:"type,omitempty"`
+	Name     string         `json:"name,omitempty"`
+	Email    string         `json:"email,omitempty"`
+	ID       string         `json:"id,omitempty"`
+	Type     string         `json:"type,omitempty"`
+	TeamRole string         `json:"team_role,omitempty"`
+	TimeZone string         `json:"time_zone,omitempty"`
+	Location *time.Location `json:"-"`
+	Country  string         `json:"country,omitempty"`
+	Region   string         `json:"region,omitempty"`
+	Weekend  []time.Weekday `json:"weekend,omitempty"`
+	// Capacity is the share of a full-time on-call load, 1 when not set.
+	Capacity float64 `json:"capacity,omitempty"`
+	// MinShifts, MaxShifts and MaxWeekends bound the shift days and week-end shifts of the user, when set.
+	MinShifts   int `json:"min_shifts,omitempty"`
+	MaxShifts   int `json:"max_shifts,omitempty"`
+	MaxWeekends int `json:"max_weekends,omitempty"`
+	// Senior and Mentors pair newbies with experienced users.
+	Senior      bool        `json:"senior,omitempty"`
+	Mentors     []string    `json:"mentors,omitempty"`
 	Unavailable []time.Time `json:"unavailable,omitempty"`
+	// IfNeeded lists the days the user is only available if needed.
+	IfNeeded []time.Time `json:"if_needed,omitempty"`
+	// Preferred and PreferredWeekdays are the days the user would rather be on call.
+	Preferred         []time.Time    `json:"preferred,omitempty"`
+	PreferredWeekdays []time.Weekday `json:"preferred_weekdays,omitempty"`
+}
+
+// Prefers tells whether the user would rather be on call the day starting at
+// d, its weekday being taken in the user local time zone.
+func (u User) Prefers(d time.Time) bool {
+	if slices.Contains(u.Preferred, d) {
+		return true
+	}
+
+	// the middle of the day is the most representative of a daily shift
+	t := d.Add(12 * time.Hour) //nolint:gomnd // half a day
+	if u.Location != nil {
+		t = t.In(u.Location)
+	}
+
+	return slices.Contains(u.PreferredWeekdays, t.Weekday())
+}
+
+// Availability of a user for a day, answered in availability polls.
+type Availability int
+
+const (
+	Available Availability = iota
+	IfNeeded
+	Unavailable
+)
+
+// Availability returns the availability of the user for the day starting at d.
+func (u User) Availability(d time.Time) Availability {
+	switch {
+	case slices.Contains(u.Unavailable, d):
+		return Unavailable
+	case slices.Contains(u.IfNeeded, d):
+		return IfNeeded
+	default:
+		return Available
+	}
+}
+
+// IsWeekend tells whether the day starting at d is a week-end day for the user,
+// in the user local time zone and with the user local week-end days.
+func (u User) IsWeekend(d time.Time, weekend Weekend) bool {
+	days := weekend.Days
+	if len(u.Weekend) > 0 {
+		days = u.Weekend
+	}
+
+	// the middle of the day is the most representative of a daily shift
+	t := d.Add(12 * time.Hour) //nolint:gomnd // half a day
+	if u.Location != nil {
+		t = t.In(u.Location)
+	}
+
+	return slices.Contains(days, t.Weekday())
 }
 
 // An AssignedUser has a name, id, and type for PagerDuty override.
@@ -105,3 +181,64 @@ func (users Users) RetrieveAssignedUserByEmail(email string) (AssignedUser, erro
 
 	return AssignedUser{}, fmt.Errorf("unknown user %s", email)
 }
+
+func (users Users) RetrieveAssignedUserByID(id string) (AssignedUser, error) {
+	for _, u := range users.Users {
+		if u.ID == id {
+			return AssignedUser{
+				Name:  u.Name,
+				Email: u.Email,
+				ID:    u.ID,
+				Type:  u.Type,
+			}, nil
+		}
+	}
+
+	return AssignedUser{}, fmt.Errorf("unknown user id %s", id)
+}
+
+// Resolve fills the name and email of overrides users known by their id only.
+func (users Users) Resolve(overrides Overrides) Overrides {
+	resolved := Overrides{
+		Overrides: make([]Override, len(overrides.Overrides)),
+	}
+
+	for i, o := range overrides.Overrides {
+		if o.User.Email == "" {
+			if u, err := users.RetrieveAssignedUserByID(o.User.ID); err == nil {
+				o.User = u
+			}
+		}
+		resolved.Overrides[i] = o
+	}
+
+	return resolved
+}
+
+// OnCallEmails returns, for every layer, the email of the user on call at t,
+// empty when the layer has no override at t. Overrides downloaded from
+// PagerDuty only hold user references, so their users are resolved by id.
+func (users Users) OnCallEmails(t time.Time, layers ...Overrides) []string {
+	emails := make([]string, len(layers))
+
+	for i, layer := range layers {
+		o, ok := layer.At(t)
+		if !ok {
+			continue
+		}
+
+		if o.User.Email != "" {
+			emails[i] = o.User.Email
+			continue
+		}
+
+		u, err := users.RetrieveAssignedUserByID(o.User.ID)
+		if err != nil {
+			log.Debug().Msgf("error: %s", err.Error())
+			continue
+		}
+		emails[i] = u.Email
+	}
+
+	return emails
+}
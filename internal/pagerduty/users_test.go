@@ -0,0 +1,48 @@
+package pagerduty
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestOnCallEmails(t *testing.T) {
+	users := Users{Users: []User{{ID: "P1", Email: "alice@email.com"}, {ID: "P2", Email: "bob@email.com"}}}
+	alice := AssignedUser{ID: "P1", Email: "alice@email.com"}
+	bob := AssignedUser{ID: "P2"}
+	at := time.Date(2024, 9, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		layers []Overrides
+		want   []string
+	}{
+		{
+			name:   "every layer on call",
+			layers: []Overrides{daily(alice, alice), daily(bob, bob)},
+			want:   []string{"alice@email.com", "bob@email.com"},
+		},
+		{
+			name:   "first layer without override",
+			layers: []Overrides{daily(alice), daily(bob, bob)},
+			want:   []string{"", "bob@email.com"},
+		},
+		{
+			name:   "unknown user",
+			layers: []Overrides{daily(alice, alice), daily(AssignedUser{ID: "P3"}, AssignedUser{ID: "P3"})},
+			want:   []string{"alice@email.com", ""},
+		},
+		{
+			name: "no layer",
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := users.OnCallEmails(at, tt.layers...); !slices.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package pagerduty
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Weekend defines the week-end days and the shape of week-end shifts.
+type Weekend struct {
+	Days []time.Weekday
+	// Split schedules week-end days as independent shifts, instead of a
+	// single shift bundling consecutive week-end days.
+	Split bool
+	// Start is the time of day the week-end starts on its first day. Zero
+	// means the usual handover time.
+	Start time.Duration
+}
+
+func DefaultWeekend() Weekend {
+	return Weekend{
+		Days: []time.Weekday{time.Saturday, time.Sunday},
+	}
+}
+
+func (w Weekend) IsWeekend(d time.Time) bool {
+	return slices.Contains(w.Days, d.Weekday())
+}
+
+// UnmarshalJSON reads week-end definitions like
+// {"days": ["Friday", "Saturday", "Sunday"], "split": false, "start": "18:00"}.
+func (w *Weekend) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Days  []string `json:"days"`
+		Split bool     `json:"split"`
+		Start string   `json:"start"`
+	}
+
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	*w = DefaultWeekend()
+	w.Split = raw.Split
+
+	if len(raw.Days) > 0 {
+		w.Days = []time.Weekday{}
+		for _, day := range raw.Days {
+			weekday, err := ParseWeekday(day)
+			if err != nil {
+				return err
+			}
+			w.Days = append(w.Days, weekday)
+		}
+	}
+
+	if raw.Start != "" {
+		w.Start, err = ParseTimeOfDay(raw.Start)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ParseWeekday reads a weekday name, either full ("Saturday") or short ("Sat").
+func ParseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(s, d.String()) || strings.EqualFold(s, d.String()[:3]) {
+			return d, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("unknown weekday %s", s)
+}
+
+// ParseTimeOfDay reads a "15:04" time of day as a duration since midnight.
+func ParseTimeOfDay(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %s: %w", s, err)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
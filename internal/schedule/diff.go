@@ -0,0 +1,39 @@
+package schedule
+
+import (
+	"github.com/fatih/color"
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func DisplayDiff(title string, changes []pagerduty.Change) {
+	added := color.New(color.FgHiGreen).Add(color.Bold)
+	removed := color.New(color.FgHiRed).Add(color.Bold)
+	changed := color.New(color.FgHiYellow).Add(color.Bold)
+
+	log.Info().Msg(title)
+	log.Info().Msg("")
+
+	if len(changes) == 0 {
+		log.Info().Msg("no change")
+	}
+
+	count := make(map[pagerduty.ChangeKind]int)
+	for _, c := range changes {
+		count[c.Kind]++
+		switch c.Kind {
+		case pagerduty.Added:
+			log.Info().Msg(added.Sprintf("+ %s %s", c.Day, c.Generated.Name))
+		case pagerduty.Removed:
+			log.Info().Msg(removed.Sprintf("- %s %s", c.Day, c.Existing.Name))
+		case pagerduty.Changed:
+			log.Info().Msg(changed.Sprintf("~ %s %s -> %s", c.Day, c.Existing.Name, c.Generated.Name))
+		}
+	}
+
+	log.Info().Msg("")
+	log.Info().Msgf("%d added, %d removed, %d changed",
+		count[pagerduty.Added], count[pagerduty.Removed], count[pagerduty.Changed])
+	log.Info().Msg("")
+}
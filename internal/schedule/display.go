@@ -35,7 +35,8 @@ var days = [7]string{
 	"Sun",
 }
 
-func DisplayCalendar(title string, schedule pagerduty.Overrides) {
+func DisplayCalendar(title string, schedule pagerduty.Overrides, weekend pagerduty.Weekend,
+	holidays pagerduty.Holidays, country string) {
 	start := schedule.Overrides[0].Start
 	month := start.Month()
 	year := start.Year()
@@ -45,13 +46,42 @@ func DisplayCalendar(title string, schedule pagerduty.Overrides) {
 	printHeader(month, year)
 
 	day := 1
-	printFirstWeek(&day, start, schedule)
-	printOtherWeeks(day, start, schedule)
+	printFirstWeek(&day, start, schedule, weekend, holidays, country)
+	printOtherWeeks(day, start, schedule, weekend, holidays, country)
 
 	log.Info().Msg("")
 	log.Info().Msg("")
 }
 
+// Slot is a follow-the-sun window of the day, displayed in its own calendar.
+type Slot struct {
+	Name  string
+	Start time.Duration
+}
+
+// DisplaySlots displays one calendar per window of the day, with the overrides
+// starting at the window start time.
+func DisplaySlots(title string, schedule pagerduty.Overrides, slots []Slot, weekend pagerduty.Weekend,
+	holidays pagerduty.Holidays, country string) {
+	for _, slot := range slots {
+		filtered := pagerduty.Overrides{
+			Overrides: []pagerduty.Override{},
+		}
+
+		for _, o := range schedule.Overrides {
+			if time.Duration(o.Start.Hour())*time.Hour+time.Duration(o.Start.Minute())*time.Minute == slot.Start {
+				filtered.Overrides = append(filtered.Overrides, o)
+			}
+		}
+
+		if len(filtered.Overrides) == 0 {
+			continue
+		}
+
+		DisplayCalendar(title+" ("+slot.Name+")", filtered, weekend, holidays, country)
+	}
+}
+
 const (
 	monthStringLen string = "   "
 	daySeparator   string = " "
@@ -66,15 +96,16 @@ func printHeader(month time.Month, year int) {
 	log.Info().Msg(c2.Sprint(strings.Join(days[:], daySeparator+strings.Repeat(" ", nameLen))))
 }
 
-func printFirstWeek(day *int, start time.Time, schedule pagerduty.Overrides) {
+func printFirstWeek(day *int, start time.Time, schedule pagerduty.Overrides, weekend pagerduty.Weekend,
+	holidays pagerduty.Holidays, country string) {
 	f := beginningOfMonth(start).Weekday()
 	found := false
 
 	line := ""
-	for i, v := range days {
+	for _, v := range days {
 		if f.String()[0:3] == v {
-			line += printDay(*day, i, start)
-			line += printName(*day, schedule)
+			line += printDay(*day, start, weekend, holidays, country)
+			line += printName(*day, start, schedule)
 			*day++
 			found = true
 			continue
@@ -83,8 +114,8 @@ func printFirstWeek(day *int, start time.Time, schedule pagerduty.Overrides) {
 		}
 
 		if found {
-			line += printDay(*day, i, start)
-			line += printName(*day, schedule)
+			line += printDay(*day, start, weekend, holidays, country)
+			line += printName(*day, start, schedule)
 			*day++
 		}
 	}
@@ -92,13 +123,14 @@ func printFirstWeek(day *int, start time.Time, schedule pagerduty.Overrides) {
 	log.Info().Msgf("%s", line)
 }
 
-func printOtherWeeks(day int, start time.Time, schedule pagerduty.Overrides) {
+func printOtherWeeks(day int, start time.Time, schedule pagerduty.Overrides, weekend pagerduty.Weekend,
+	holidays pagerduty.Holidays, country string) {
 	e := endOfMonth(start)
 	idx := 0
 	line := ""
 	for day <= e.Day() {
-		line += printDay(day, idx, start)
-		line += printName(day, schedule)
+		line += printDay(day, start, weekend, holidays, country)
+		line += printName(day, start, schedule)
 		idx++
 
 		if idx >= len(days) {
@@ -111,8 +143,8 @@ func printOtherWeeks(day int, start time.Time, schedule pagerduty.Overrides) {
 	}
 }
 
-func printName(idx int, schedule pagerduty.Overrides) string {
-	firstName := strings.Split(schedule.Overrides[idx-1].User.Name, " ")[0]
+func printName(day int, start time.Time, schedule pagerduty.Overrides) string {
+	firstName := strings.Split(overrideOn(day, start, schedule).User.Name, " ")[0]
 	if len(firstName) > nameLen {
 		firstName = firstName[:nameLen]
 	} else {
@@ -122,15 +154,34 @@ func printName(idx int, schedule pagerduty.Overrides) string {
 	return firstName
 }
 
-func printDay(day, idx int, start time.Time) string {
+// overrideOn returns the override starting on a day of the month, or else
+// the one covering the middle of that day.
+func overrideOn(day int, start time.Time, schedule pagerduty.Overrides) pagerduty.Override {
+	for _, o := range schedule.Overrides {
+		if o.Start.Year() == start.Year() && o.Start.Month() == start.Month() && o.Start.Day() == day {
+			return o
+		}
+	}
+
+	o, _ := schedule.At(time.Date(start.Year(), start.Month(), day, 12, 0, 0, 0, start.Location())) //nolint:gomnd // noon
+	return o
+}
+
+func printDay(day int, start time.Time, weekend pagerduty.Weekend, holidays pagerduty.Holidays, country string) string {
+	date := time.Date(start.Year(), start.Month(), day, start.Hour(), start.Minute(), 0, 0, start.Location())
+	isWeekend := weekend.IsWeekend(date)
+	isPublicHoliday := holidays.IsHoliday(country, date)
 	workdayColor := color.New(color.FgWhite).Add(color.Bold)
 	holidayColor := color.New(color.FgHiCyan).Add(color.Bold)
+	publicHolidayColor := color.New(color.FgHiMagenta).Add(color.Bold)
 	currentDayColor := color.New(color.FgHiRed).Add(color.Bold)
 
 	if day > 9 { //nolint:gomnd // obvious value
 		if day == start.Day() {
 			return currentDayColor.Sprintf(" %d%s", day, daySeparator)
-		} else if idx == 5 || idx == 6 {
+		} else if isPublicHoliday {
+			return publicHolidayColor.Sprintf(" %d%s", day, daySeparator)
+		} else if isWeekend {
 			return holidayColor.Sprintf(" %d%s", day, daySeparator)
 		} else {
 			return workdayColor.Sprintf(" %d%s", day, daySeparator)
@@ -138,7 +189,9 @@ func printDay(day, idx int, start time.Time) string {
 	} else {
 		if day == start.Day() {
 			return currentDayColor.Sprintf("  %d%s", day, daySeparator)
-		} else if idx == 5 || idx == 6 {
+		} else if isPublicHoliday {
+			return publicHolidayColor.Sprintf("  %d%s", day, daySeparator)
+		} else if isWeekend {
 			return holidayColor.Sprintf("  %d%s", day, daySeparator)
 		} else {
 			return workdayColor.Sprintf("  %d%s", day, daySeparator)
@@ -0,0 +1,169 @@
+package solver
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// Rejection reasons of a user for a shift.
+const (
+	Unavailable     string = "unavailable"
+	PartlyAvailable string = "unavailable some days of the shift"
+	IfNeededOnly    string = "available if needed only"
+	Newbie          string = "newbie"
+	NotInLayer      string = "not in layer emails"
+	RoleNotAllowed  string = "team role not allowed"
+	OutsideRegion   string = "outside window regions"
+	TooManyHolidays string = "too many public holidays"
+	TooManyWeekends string = "too many week-ends"
+	StatsTooHigh    string = "stats too high"
+	UnknownUser     string = "unknown in users JSON"
+	WorkedPrevious  string = "worked previous shift"
+	OtherLayer      string = "already on another layer"
+)
+
+// Rejection tells why a user was not selected.
+type Rejection struct {
+	Email  string
+	Reason string
+}
+
+// InfeasibleError is returned when no user can be selected for a layer of a
+// shift, with the reason each user was rejected for.
+type InfeasibleError struct {
+	Layer      string
+	Day        time.Time
+	Days       int
+	Rejections []Rejection
+}
+
+func (e *InfeasibleError) Error() string {
+	if e.Days <= 1 {
+		return fmt.Sprintf("empty user for %s on %s", e.Layer, e.Day)
+	}
+
+	// users available some days only would take a shorter shift
+	if slices.ContainsFunc(e.Rejections, func(r Rejection) bool { return r.Reason == PartlyAvailable }) &&
+		!slices.ContainsFunc(e.Rejections, func(r Rejection) bool { return r.Reason == "" }) {
+		return fmt.Sprintf("empty user for %s on the %d days shift from %s: no user is available every day of the shift", e.Layer, e.Days, e.Day)
+	}
+
+	return fmt.Sprintf("empty user for %s on the %d days shift from %s", e.Layer, e.Days, e.Day)
+}
+
+// diagnose explains why no user could be selected for a layer of a shift,
+// previous users being the last users of the shift window and taken users the
+// users already selected on other layers and windows, unpaired users leaving a
+// newbie without mentor.
+func (s *Solver) diagnose(layer int, sh shift, previous, taken []pagerduty.AssignedUser, unpaired []string) *InfeasibleError {
+	days := sh.coveredDays()
+	e := &InfeasibleError{
+		Layer: s.layers[layer].Name,
+		Day:   sh.Start,
+		Days:  len(days),
+	}
+
+	for _, user := range s.input.Users {
+		reason := s.ineligibility(layer, sh, user)
+		if reason == "" {
+			reason = s.rejection(sh, user, false, true)
+		}
+
+		if reason == Unavailable && slices.ContainsFunc(days, func(d time.Time) bool { return !slices.Contains(user.Unavailable, d) }) {
+			reason = PartlyAvailable
+		}
+
+		if reason == "" {
+			u, err := s.users.RetrieveAssignedUser(user)
+			switch {
+			case err != nil:
+				reason = UnknownUser
+			case slices.Contains(previous, u):
+				reason = WorkedPrevious
+			case slices.Contains(taken, u):
+				reason = OtherLayer
+			case slices.Contains(unpaired, user.Email):
+				reason = Unpaired
+			case s.apartFrom(user.Email, taken):
+				reason = NeverTogetherWith
+			}
+		}
+
+		e.Rejections = append(e.Rejections, Rejection{Email: user.Email, Reason: reason})
+	}
+
+	return e
+}
+
+// ineligibility returns why a user is not eligible for a layer during a shift, if so.
+func (s *Solver) ineligibility(layer int, sh shift, user pagerduty.User) string {
+	if reason := s.layerIneligibility(s.layers[layer], user); reason != "" {
+		return reason
+	}
+
+	if sh.window != nil && slices.Contains(s.windowExcluded[sh.Window], user.Email) {
+		return OutsideRegion
+	}
+
+	return ""
+}
+
+// Coverage counts the users available a day of the schedule, overall and per
+// layer, against the number of users needed.
+type Coverage struct {
+	Day       time.Time
+	Needed    int
+	Available int
+	Layers    []int
+}
+
+// UnderCovered tells whether the day can not be covered: not enough users are
+// available, or no eligible user is available for a layer.
+func (c Coverage) UnderCovered() bool {
+	return c.Available < c.Needed || slices.Contains(c.Layers, 0)
+}
+
+// Tight tells whether the day leaves little choice: users of the previous or
+// next day can not cover it.
+func (c Coverage) Tight() bool {
+	return c.Available < 2*c.Needed
+}
+
+// Coverage computes, before solving, the users available each day of the
+// schedule.
+func (s *Solver) Coverage() []Coverage {
+	coverage := []Coverage{}
+
+	for d := s.input.ScheduleStart; d.Before(s.input.ScheduleEnd.Add(utils.OneDay)); d = d.Add(utils.OneDay) {
+		c := Coverage{
+			Day:    d,
+			Needed: len(s.layers) * max(1, len(s.windows)),
+			Layers: make([]int, len(s.layers)),
+		}
+
+		for _, user := range s.input.Users {
+			if slices.Contains(user.Unavailable, d) {
+				continue
+			}
+
+			if _, err := s.users.RetrieveAssignedUser(user); err != nil {
+				continue
+			}
+
+			c.Available++
+			for i := range s.layers {
+				if !slices.Contains(s.excludedUsers[i], user.Email) {
+					c.Layers[i]++
+				}
+			}
+		}
+
+		coverage = append(coverage, c)
+	}
+
+	return coverage
+}
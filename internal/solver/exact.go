@@ -0,0 +1,681 @@
+package solver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+const (
+	// ExactMaxNodes bounds the number of partial assignments explored by the
+	// exact engine, the best schedule found so far is kept beyond.
+	ExactMaxNodes int = 2_000_000
+	// WeekendWeight and HolidayWeight weigh week-end and public holiday shifts
+	// balance against shifts balance in the exact engine objective.
+	WeekendWeight int = 4
+	HolidayWeight int = 4
+	// IfNeededCost is the objective increase of assigning a shift to a user
+	// available if needed only, in full-time user units.
+	IfNeededCost int = 10
+	// MinShiftsCost is the objective increase per shift day missing to a user
+	// below its minimum, in full-time user units.
+	MinShiftsCost int = 50
+	// MissedPreferenceCost is the objective increase of a shift a user would
+	// rather be on call but does not get, in full-time user units.
+	MissedPreferenceCost int = 5
+	// exactMaxMasks bounds the user sets per shift used to propagate feasibility.
+	exactMaxMasks int = 4096
+	// exactFullTime is the weight of a full-time user in the exact engine
+	// objective, part-time users weighing more.
+	exactFullTime float64 = 100
+)
+
+// ErrNoSchedule is returned when no schedule satisfies the hard constraints.
+var ErrNoSchedule = errors.New("no schedule satisfies availabilities and eligibility rules")
+
+// slot is a layer of a shift, to be filled by one of its candidates.
+type slot struct {
+	shift      int
+	layer      int
+	candidates []int
+	// fixed slots continue the previous schedule, or are pinned.
+	fixed  int
+	pinned bool
+	// conflicts lists the shifts whose users can not take this slot.
+	conflicts []int
+}
+
+// search holds the state of the exact engine depth first search.
+type search struct {
+	s        *Solver
+	shifts   []shift
+	slots    []slot
+	assigned []pagerduty.AssignedUser
+	// last users of the previous schedule, forbidden on first shifts.
+	last []int
+	// per shift and user days, week-end, public holiday and if needed counts.
+	days     []int
+	weekend  [][]int
+	holiday  [][]int
+	ifNeeded [][]int
+	prefers  [][]int
+	current  [][]int
+	best     [][]int
+	stats    []int
+	weekends []int
+	holidays []int
+	// weight of users terms in the objective, inversely proportional to their capacity.
+	weight    []int
+	minWeight int
+	cost      int
+	bestCost  int
+	// remaining days, week-ends and holidays lower bounds from a slot.
+	remDays     []int
+	remWeekends []int
+	remHolidays []int
+	// feasible user sets of each shift, nil when unknown.
+	feasible []map[uint64]bool
+	nodes    int
+}
+
+// exact assigns users to shifts with a depth first search, pruned by a lower
+// bound of the fairness objective: the sum of squared shifts, week-end shifts
+// and public holiday shifts counts of users, weighted by the inverse of their
+// capacity so that part-time users get proportionally less shifts, plus a
+// cost per shift of a user available if needed only and per shift day
+// missing to users below their minimum. It proves there is no
+// schedule when the search space is exhausted without finding one.
+func (s *Solver) exact(shifts []shift) (assignment, error) {
+	e := s.newSearch(shifts)
+
+	for _, sl := range e.slots {
+		if sl.fixed < 0 && len(sl.candidates) == 0 {
+			previous := []pagerduty.AssignedUser{}
+			if sl.shift < max(1, len(s.windows)) {
+				previous = s.lastAssignedUsers
+			}
+
+			return nil, fmt.Errorf("%w: %w", ErrNoSchedule, s.diagnose(sl.layer, shifts[sl.shift], previous, nil, nil))
+		}
+	}
+
+	if k, ok := e.reachable(); !ok {
+		return nil, fmt.Errorf("%w: no available users from %s", ErrNoSchedule, shifts[k].Start)
+	}
+
+	e.dfs(0)
+
+	if e.best == nil {
+		if e.nodes >= ExactMaxNodes {
+			return nil, fmt.Errorf("no schedule found within %d search nodes", ExactMaxNodes)
+		}
+		return nil, ErrNoSchedule
+	}
+
+	if e.nodes >= ExactMaxNodes {
+		log.Info().Msgf("exact engine: best schedule found within %d search nodes (cost %d)", ExactMaxNodes, e.bestCost)
+	} else {
+		log.Info().Msgf("exact engine: optimal schedule found in %d search nodes (cost %d)", e.nodes, e.bestCost)
+	}
+
+	return e.result(), nil
+}
+
+func (s *Solver) newSearch(shifts []shift) *search {
+	users := s.input.Users
+	e := &search{
+		s:        s,
+		shifts:   shifts,
+		assigned: make([]pagerduty.AssignedUser, len(users)),
+		days:     make([]int, len(shifts)),
+		weekend:  make([][]int, len(shifts)),
+		holiday:  make([][]int, len(shifts)),
+		ifNeeded: make([][]int, len(shifts)),
+		prefers:  make([][]int, len(shifts)),
+		current:  make([][]int, len(shifts)),
+		stats:    make([]int, len(users)),
+		weekends: make([]int, len(users)),
+		holidays: make([]int, len(users)),
+		weight:   make([]int, len(users)),
+		bestCost: math.MaxInt,
+	}
+
+	e.minWeight = math.MaxInt
+	for u, user := range users {
+		e.weight[u] = int(math.Round(exactFullTime / capacity(user)))
+		e.minWeight = min(e.minWeight, e.weight[u])
+	}
+
+	known := make([]bool, len(users))
+	for u, user := range users {
+		a, err := s.users.RetrieveAssignedUser(user)
+		if err != nil {
+			continue
+		}
+		e.assigned[u] = a
+		known[u] = true
+
+		if slices.Contains(s.lastAssignedUsers, a) {
+			e.last = append(e.last, u)
+		}
+	}
+
+	windows := max(1, len(s.windows))
+
+	for k, sh := range shifts {
+		e.days[k] = len(sh.Days)
+		e.weekend[k] = make([]int, len(users))
+		e.holiday[k] = make([]int, len(users))
+		e.ifNeeded[k] = make([]int, len(users))
+		e.prefers[k] = make([]int, len(users))
+		e.current[k] = make([]int, len(s.layers))
+
+		for u, user := range users {
+			if sh.isWeekendFor(user, s.weekend) {
+				e.weekend[k][u] = 1
+			}
+			if sh.isHolidayFor(user, s.holidays) {
+				e.holiday[k][u] = 1
+			}
+			if sh.isIfNeededFor(user) {
+				e.ifNeeded[k][u] = 1
+			}
+			if sh.isPreferredBy(user) {
+				e.prefers[k][u] = 1
+			}
+		}
+
+		// a user can not take two consecutive shifts of a window, nor two windows of a
+		// shift, nor the first window of a day after the last window of the previous day
+		conflicts := []int{}
+		if k >= windows {
+			conflicts = append(conflicts, k-windows)
+		}
+		if windows > 1 && k%windows == 0 && k > 0 {
+			conflicts = append(conflicts, k-1)
+		}
+		for j := k - k%windows; j < k; j++ {
+			conflicts = append(conflicts, j)
+		}
+
+		continued := sh.Continued && s.onEveryLayer(s.lastAssignedUsers)
+
+		for l := range s.layers {
+			sl := slot{shift: k, layer: l, fixed: -1, conflicts: conflicts}
+
+			if continued {
+				sl.fixed = slices.Index(e.assigned, s.lastAssignedUsers[l])
+				if sl.fixed >= 0 {
+					e.current[k][l] = sl.fixed
+					e.stats[sl.fixed] += len(sh.Days)
+				}
+			}
+
+			// pinned users count in stats like assigned ones
+			if s.isPinned(k, l) {
+				u := slices.Index(e.assigned, s.pinning[k][l])
+				sl.fixed, sl.pinned = u, true
+				e.current[k][l] = u
+				e.stats[u] += len(sh.Days)
+				e.weekends[u] += e.weekend[k][u]
+				e.holidays[u] += e.holiday[k][u]
+			}
+
+			excluded := append(slices.Clip(s.excludedFor(l, sh)), s.pinnedNear(k)...)
+			for u, user := range users {
+				if known[u] && !slices.Contains(excluded, user.Email) && sh.isAvailable(user) {
+					sl.candidates = append(sl.candidates, u)
+				}
+			}
+
+			e.slots = append(e.slots, sl)
+		}
+	}
+
+	// lower bounds of what remains to be assigned from each slot
+	e.remDays = make([]int, len(e.slots)+1)
+	e.remWeekends = make([]int, len(e.slots)+1)
+	e.remHolidays = make([]int, len(e.slots)+1)
+
+	for i := len(e.slots) - 1; i >= 0; i-- {
+		sl := e.slots[i]
+		e.remDays[i] = e.remDays[i+1]
+		e.remWeekends[i] = e.remWeekends[i+1]
+		e.remHolidays[i] = e.remHolidays[i+1]
+
+		if sl.fixed >= 0 {
+			continue
+		}
+
+		e.remDays[i] += e.days[sl.shift]
+		if e.always(e.weekend[sl.shift], sl.candidates) {
+			e.remWeekends[i]++
+		}
+		if e.always(e.holiday[sl.shift], sl.candidates) {
+			e.remHolidays[i]++
+		}
+	}
+
+	// previous months debt counts as if shifts were already assigned
+	for u, user := range users {
+		debt := s.Debt[user.Email]
+		e.stats[u] += debt.Shifts
+		e.weekends[u] += debt.Weekends
+		e.holidays[u] += debt.Holidays
+		e.cost += e.weight[u] * (e.stats[u]*e.stats[u] + WeekendWeight*e.weekends[u]*e.weekends[u] + HolidayWeight*e.holidays[u]*e.holidays[u])
+		e.cost += int(exactFullTime) * MinShiftsCost * e.missing(u)
+	}
+
+	// preferred shifts are missed until given, a user taking one window of a day at most
+	for k := 0; k < len(shifts); k += windows {
+		if shifts[k].Continued {
+			continue
+		}
+
+		for u, user := range users {
+			pinned := slices.ContainsFunc(s.pinning[k:k+windows], func(p []pagerduty.AssignedUser) bool {
+				return slices.Contains(p, e.assigned[u])
+			})
+			if shifts[k].isPreferredBy(user) && !pinned {
+				e.cost += int(exactFullTime) * MissedPreferenceCost
+			}
+		}
+	}
+
+	return e
+}
+
+// always tells whether a flag is set for all candidates.
+func (e *search) always(flags []int, candidates []int) bool {
+	for _, u := range candidates {
+		if flags[u] == 0 {
+			return false
+		}
+	}
+
+	return len(candidates) > 0
+}
+
+func (e *search) dfs(i int) {
+	e.nodes++
+	if e.nodes >= ExactMaxNodes {
+		return
+	}
+
+	if i == len(e.slots) {
+		if e.cost < e.bestCost {
+			e.bestCost = e.cost
+			e.best = make([][]int, len(e.current))
+			for k := range e.current {
+				e.best[k] = slices.Clone(e.current[k])
+			}
+		}
+		return
+	}
+
+	if e.bound(i) >= e.bestCost {
+		return
+	}
+
+	sl := e.slots[i]
+	if sl.fixed >= 0 {
+		e.dfs(i + 1)
+		return
+	}
+
+	// cheapest candidates first
+	candidates := []int{}
+	for _, u := range sl.candidates {
+		if e.allowed(sl, u) {
+			candidates = append(candidates, u)
+		}
+	}
+
+	// ties between candidates are broken at random
+	e.s.rand.Shuffle(len(candidates), func(a, b int) { candidates[a], candidates[b] = candidates[b], candidates[a] })
+
+	delta := make(map[int]int, len(candidates))
+	for _, u := range candidates {
+		delta[u] = e.delta(sl.shift, u)
+	}
+	slices.SortStableFunc(candidates, func(a, b int) int { return delta[a] - delta[b] })
+
+	for _, u := range candidates {
+		e.assign(sl, u, 1)
+		if sl.layer < len(e.s.layers)-1 || e.canContinue(sl.shift) {
+			e.dfs(i + 1)
+		}
+		e.assign(sl, u, -1)
+
+		if e.nodes >= ExactMaxNodes {
+			return
+		}
+	}
+}
+
+// reachable computes, from the last shift to the first one, the sets of
+// users that can fill each shift while leaving users for the next shift of
+// its window. It returns the last shift that can not be filled, if any.
+func (e *search) reachable() (int, bool) {
+	windows := max(1, len(e.s.windows))
+	e.feasible = make([]map[uint64]bool, len(e.shifts))
+
+	// sets are stored as bit masks
+	if len(e.assigned) > 64 { //nolint:gomnd // bits of uint64
+		return 0, true
+	}
+
+	for k := len(e.shifts) - 1; k >= 0; k-- {
+		masks := e.masks(k)
+		if masks == nil {
+			continue
+		}
+
+		var next map[uint64]bool
+		if k+windows < len(e.shifts) {
+			next = e.feasible[k+windows]
+		}
+
+		feasible := make(map[uint64]bool)
+		for _, m := range masks {
+			ok := next == nil
+			for n := range next {
+				if n&m == 0 {
+					ok = true
+					break
+				}
+			}
+
+			if ok {
+				feasible[m] = true
+			}
+		}
+
+		if len(feasible) == 0 {
+			return k, false
+		}
+		e.feasible[k] = feasible
+	}
+
+	return 0, true
+}
+
+// masks lists the sets of users that can fill a shift, or nil when there are
+// too many of them.
+func (e *search) masks(k int) []uint64 {
+	masks := []uint64{}
+	seen := make(map[uint64]bool)
+	slots := e.slots[k*len(e.s.layers) : (k+1)*len(e.s.layers)]
+
+	var fill func(l int, mask uint64) bool
+	fill = func(l int, mask uint64) bool {
+		if l == len(slots) {
+			if !seen[mask] {
+				seen[mask] = true
+				masks = append(masks, mask)
+			}
+			return len(masks) <= exactMaxMasks
+		}
+
+		candidates := slots[l].candidates
+		if slots[l].fixed >= 0 {
+			candidates = []int{slots[l].fixed}
+		}
+
+		for _, u := range candidates {
+			if mask&(1<<u) != 0 || (slots[l].fixed < 0 && k < max(1, len(e.s.windows)) && slices.Contains(e.last, u)) {
+				continue
+			}
+
+			if !fill(l+1, mask|1<<u) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if !fill(0, 0) {
+		return nil
+	}
+
+	return masks
+}
+
+// canContinue tells whether the users of a filled shift leave users for the
+// next shift of its window.
+func (e *search) canContinue(k int) bool {
+	if e.feasible[k] == nil {
+		return true
+	}
+
+	var mask uint64
+	for _, u := range e.current[k] {
+		mask |= 1 << u
+	}
+
+	return e.feasible[k][mask]
+}
+
+// allowed tells whether a user can take a slot, given the users already assigned.
+func (e *search) allowed(sl slot, u int) bool {
+	if sl.shift < max(1, len(e.s.windows)) && slices.Contains(e.last, u) {
+		return false
+	}
+
+	onShift := e.onShift(sl)
+	if slices.Contains(onShift, u) {
+		return false
+	}
+
+	for _, j := range sl.conflicts {
+		if slices.Contains(e.current[j], u) {
+			return false
+		}
+	}
+
+	// quotas apply to the month, without previous months debt
+	user := e.s.input.Users[u]
+	debt := e.s.Debt[user.Email]
+	weekend := e.weekend[sl.shift][u] == 1
+	if user.MaxShifts > 0 && e.stats[u]-debt.Shifts+e.days[sl.shift] > user.MaxShifts {
+		return false
+	}
+	if user.MaxWeekends > 0 && weekend && e.weekends[u]-debt.Weekends >= user.MaxWeekends {
+		return false
+	}
+
+	if len(e.s.apart) > 0 {
+		sameDay := []pagerduty.AssignedUser{}
+		for _, v := range onShift {
+			if v >= 0 {
+				sameDay = append(sameDay, e.assigned[v])
+			}
+		}
+		for j := sl.shift - sl.shift%max(1, len(e.s.windows)); j < sl.shift; j++ {
+			for _, v := range e.current[j] {
+				sameDay = append(sameDay, e.assigned[v])
+			}
+		}
+
+		if e.s.apartFrom(user.Email, sameDay) {
+			return false
+		}
+	}
+
+	if e.s.mentoring() {
+		emails := make([]string, len(e.s.layers))
+		for l, v := range onShift {
+			if v >= 0 {
+				emails[l] = e.s.input.Users[v].Email
+			}
+		}
+		emails[sl.layer] = user.Email
+
+		if e.s.unpaired(emails) {
+			return false
+		}
+	}
+
+	if e.s.hasRules() && e.s.ruleViolation(e.shifts[sl.shift], user, e.duties(sl.shift, u)) != "" {
+		return false
+	}
+
+	return true
+}
+
+// onShift returns the users of the layers of a slot shift, -1 for the slot
+// layer and the layers not filled yet: layers are filled in order, fixed ones
+// from the start of the search.
+func (e *search) onShift(sl slot) []int {
+	users := make([]int, len(e.s.layers))
+	for l, v := range e.current[sl.shift] {
+		users[l] = -1
+		if l < sl.layer || (l > sl.layer && e.slots[sl.shift*len(e.s.layers)+l].fixed >= 0) {
+			users[l] = v
+		}
+	}
+
+	return users
+}
+
+// duties returns the days of the shifts of a user assigned before a shift,
+// within the rules lookback, history and shifts pinned after it included.
+func (e *search) duties(k, u int) [][]time.Time {
+	email := e.s.input.Users[u].Email
+	duties := append(slices.Clone(e.s.history[email]), e.s.pinnedAfter(e.shifts[k], email)...)
+	from := e.shifts[k].Days[0].Add(-time.Duration(e.s.rules.Lookback()) * utils.OneDay)
+
+	for j := k - 1; j >= 0 && !e.shifts[j].Days[len(e.shifts[j].Days)-1].Before(from); j-- {
+		if slices.Contains(e.current[j], u) {
+			duties = append(duties, e.shifts[j].Days)
+		}
+	}
+
+	return duties
+}
+
+// missing returns the shift days missing to a user below its minimum, without
+// previous months debt.
+func (e *search) missing(u int) int {
+	user := e.s.input.Users[u]
+	if user.MinShifts == 0 {
+		return 0
+	}
+
+	return max(0, user.MinShifts-(e.stats[u]-e.s.Debt[user.Email].Shifts))
+}
+
+// delta returns the objective increase of assigning a user to a shift.
+func (e *search) delta(k, u int) int {
+	n := e.days[k]
+	d := 2*e.stats[u]*n + n*n
+	if e.weekend[k][u] == 1 {
+		d += WeekendWeight * (2*e.weekends[u] + 1)
+	}
+	if e.holiday[k][u] == 1 {
+		d += HolidayWeight * (2*e.holidays[u] + 1)
+	}
+
+	// shift days missing to a user below its minimum are costly until assigned
+	return e.weight[u]*d + int(exactFullTime)*(IfNeededCost*e.ifNeeded[k][u]-MinShiftsCost*min(n, e.missing(u))-
+		MissedPreferenceCost*e.prefers[k][u])
+}
+
+func (e *search) assign(sl slot, u, sign int) {
+	k := sl.shift
+	if sign < 0 {
+		e.stats[u] -= e.days[k]
+		e.weekends[u] -= e.weekend[k][u]
+		e.holidays[u] -= e.holiday[k][u]
+		e.cost -= e.delta(k, u)
+		e.current[k][sl.layer] = -1
+		return
+	}
+
+	e.cost += e.delta(k, u)
+	e.stats[u] += e.days[k]
+	e.weekends[u] += e.weekend[k][u]
+	e.holidays[u] += e.holiday[k][u]
+	e.current[k][sl.layer] = u
+}
+
+// bound returns a lower bound of the objective of any complete assignment,
+// spreading remaining shifts as evenly as possible between all users, all
+// weighing as full-time users.
+func (e *search) bound(i int) int {
+	return e.minWeight * (spread(e.stats, e.remDays[i]) +
+		WeekendWeight*spread(e.weekends, e.remWeekends[i]) +
+		HolidayWeight*spread(e.holidays, e.remHolidays[i]))
+}
+
+// spread returns the minimal sum of squares of values once extra units are
+// added to them.
+func spread(values []int, extra int) int {
+	if len(values) == 0 {
+		return 0
+	}
+
+	v := slices.Clone(values)
+	slices.Sort(v)
+
+	for i := 1; extra > 0; {
+		// raise the i lowest values to the next level
+		for i < len(v) && v[i] == v[0] {
+			i++
+		}
+
+		if i < len(v) && (v[i]-v[0])*i <= extra {
+			extra -= (v[i] - v[0]) * i
+			for j := 0; j < i; j++ {
+				v[j] = v[i]
+			}
+			continue
+		}
+
+		for j := 0; j < i; j++ {
+			v[j] += extra / i
+			if j < extra%i {
+				v[j]++
+			}
+		}
+		extra = 0
+	}
+
+	sum := 0
+	for _, x := range v {
+		sum += x * x
+	}
+
+	return sum
+}
+
+// result updates users stats and returns the best assignment found.
+func (e *search) result() assignment {
+	a := assignment{}
+
+	for k, users := range e.best {
+		selected := make([]pagerduty.AssignedUser, len(users))
+		for l, u := range users {
+			selected[l] = e.assigned[u]
+			user := e.s.input.Users[u]
+			e.s.Stats[user.Email] += e.days[k]
+
+			if sl := e.slots[k*len(users)+l]; sl.fixed >= 0 && !sl.pinned {
+				continue
+			}
+			e.s.WeekendStats[user.Email] += e.weekend[k][u]
+			e.s.HolidayStats[user.Email] += e.holiday[k][u]
+		}
+		a = append(a, selected)
+	}
+
+	return a
+}
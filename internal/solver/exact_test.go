@@ -0,0 +1,269 @@
+package solver
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// objective returns the exact engine objective of an assignment.
+func objective(s *Solver, shifts []shift, a assignment) int {
+	e := s.newSearch(shifts)
+	for _, sl := range e.slots {
+		if sl.fixed < 0 {
+			e.assign(sl, slices.Index(e.assigned, a[sl.shift][sl.layer]), 1)
+		}
+	}
+
+	return e.cost
+}
+
+// bruteForce returns the lowest objective of the assignments breaking no hard
+// constraint, trying every available user on every slot, and whether there
+// is one.
+func bruteForce(s *Solver, shifts []shift) (int, bool) {
+	a := make(assignment, len(shifts))
+	for k := range a {
+		a[k] = make([]pagerduty.AssignedUser, len(s.layers))
+	}
+
+	continued := shifts[0].Continued && s.onEveryLayer(s.lastAssignedUsers)
+	best, found := math.MaxInt, false
+
+	var fill func(i int)
+	fill = func(i int) {
+		if i == len(shifts)*len(s.layers) {
+			if len(violations(s, shifts, a)) == 0 {
+				best, found = min(best, objective(s, shifts, a)), true
+			}
+			return
+		}
+
+		k, l := i/len(s.layers), i%len(s.layers)
+		options := []pagerduty.AssignedUser{}
+		switch {
+		case k == 0 && continued:
+			options = append(options, s.lastAssignedUsers[l])
+		case s.isPinned(k, l):
+			options = append(options, s.pinning[k][l])
+		default:
+			for _, user := range s.input.Users {
+				u, err := s.users.RetrieveAssignedUser(user)
+				if err == nil && shifts[k].isAvailable(user) && !slices.Contains(a[k][:l], u) {
+					options = append(options, u)
+				}
+			}
+		}
+
+		for _, u := range options {
+			a[k][l] = u
+			fill(i + 1)
+		}
+		a[k][l] = pagerduty.AssignedUser{}
+	}
+
+	fill(0)
+
+	return best, found
+}
+
+func TestExactOptimal(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(cfg *config.Config)
+		users   func(users []pagerduty.User)
+		n       int
+		from    int
+		days    int
+		newbies []string
+		pins    []config.Pin
+		pairs   []config.Pair
+	}{
+		{
+			name: "daily",
+			n:    4,
+			days: 4,
+			cfg: func(cfg *config.Config) {
+				half := 0.5
+				cfg.Users = map[string]config.User{email(3): {Capacity: &half}}
+			},
+			users: func(users []pagerduty.User) {
+				users[0].Unavailable = []time.Time{day(1)}
+				users[1].IfNeeded = []time.Time{day(2)}
+				users[3].Preferred = []time.Time{day(3)}
+			},
+		},
+		{
+			name: "week-end bundled",
+			cfg: func(cfg *config.Config) {
+				cfg.Shift.Length = config.WeekendBundled
+				cfg.Users = map[string]config.User{email(5): {MinShifts: 3}}
+			},
+			n:     5,
+			from:  3,
+			days:  5,
+			users: func(users []pagerduty.User) { users[0].Unavailable = []time.Time{day(5)} },
+		},
+		{
+			name: "pinned secondary",
+			n:    5,
+			days: 4,
+			pins: []config.Pin{{Date: day(2).Format(time.DateOnly), Layer: "secondary", User: email(1)}},
+		},
+		{
+			name: "rules and quotas",
+			cfg: func(cfg *config.Config) {
+				cfg.Rules.MinRestDays = 1
+				cfg.Users = map[string]config.User{email(2): {MaxShifts: 1}}
+			},
+			n:    5,
+			days: 4,
+			users: func(users []pagerduty.User) {
+				// preferred days would give the capped user more shifts
+				users[1].Preferred = []time.Time{day(0), day(2)}
+				users[2].Unavailable = []time.Time{day(0)}
+			},
+		},
+		{
+			name: "mentoring and never together",
+			cfg: func(cfg *config.Config) {
+				cfg.Layers[1].Mentor = true
+				cfg.Users = map[string]config.User{email(1): {Mentors: []string{email(2)}}, email(3): {Senior: true}}
+			},
+			n:       5,
+			days:    4,
+			newbies: []string{email(1)},
+			pairs:   []config.Pair{{Users: []string{email(4), email(5)}, Rule: config.NeverTogether}},
+		},
+		{
+			name: "more than 64 users",
+			n:    66,
+			days: 4,
+			users: func(users []pagerduty.User) {
+				users[0].Unavailable = []time.Time{day(2)}
+				for i := 4; i < len(users); i++ {
+					users[i].Unavailable = firstDays(4)
+				}
+			},
+			pins: []config.Pin{{Date: day(3).Format(time.DateOnly), Layer: "secondary", User: email(2)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			if tt.cfg != nil {
+				tt.cfg(&cfg)
+			}
+
+			users := team(tt.n)
+			if tt.users != nil {
+				tt.users(users)
+			}
+
+			s := newTestSolver(t, cfg, users, tt.from+tt.days, tt.newbies, nil)
+			s.input.ScheduleStart = day(tt.from)
+			s.SetConstraints(config.Constraints{Pairs: tt.pairs})
+			if err := s.SetPins(config.Pins{Pins: tt.pins}); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.SetEngine(Exact); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := violations(s, s.schedule, s.assignment); len(v) > 0 {
+				t.Errorf("got hard constraints violations %q", v)
+			}
+
+			want, ok := bruteForce(s, s.schedule)
+			if !ok {
+				t.Fatal("brute force found no schedule")
+			}
+			if got := objective(s, s.schedule, s.assignment); got != want {
+				t.Errorf("got objective %d, want the brute force optimum %d", got, want)
+			}
+		})
+	}
+}
+
+func TestExactInfeasible(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		days  int
+		users func(users []pagerduty.User)
+		// diagnosed errors tell the layer and day no user can take
+		diagnosed bool
+	}{
+		{
+			name: "no user available",
+			n:    4,
+			days: 3,
+			users: func(users []pagerduty.User) {
+				for i := range users {
+					users[i].Unavailable = []time.Time{day(1)}
+				}
+			},
+			diagnosed: true,
+		},
+		{
+			name: "not enough users for consecutive shifts",
+			n:    3,
+			days: 2,
+		},
+		{
+			name: "not enough users for consecutive shifts, more than 64 users",
+			n:    66,
+			days: 3,
+			users: func(users []pagerduty.User) {
+				for i := 3; i < len(users); i++ {
+					users[i].Unavailable = firstDays(3)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := team(tt.n)
+			if tt.users != nil {
+				tt.users(users)
+			}
+
+			s := newTestSolver(t, testConfig(), users, tt.days, nil, nil)
+			if err := s.SetEngine(Exact); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := s.Run()
+			if !errors.Is(err, ErrNoSchedule) {
+				t.Fatalf("got error %v, want %v", err, ErrNoSchedule)
+			}
+
+			var infeasible *InfeasibleError
+			if tt.diagnosed {
+				if !errors.As(err, &infeasible) {
+					t.Fatalf("got error %v, want an infeasible error", err)
+				}
+				if !infeasible.Day.Equal(day(1)) {
+					t.Errorf("got no user diagnosed on %s, want %s", infeasible.Day, day(1))
+				}
+			}
+
+			shifts := s.shifts()
+			s.pin(shifts)
+			if _, ok := bruteForce(s, shifts); ok {
+				t.Error("brute force found a schedule")
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package solver
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// ImproveTemperature is the initial simulated annealing temperature, in
+	// score points: worse schedules are accepted with a probability of
+	// exp(-delta/temperature), the temperature decreasing to zero.
+	ImproveTemperature float64 = 1
+	// DefaultImproveIterations is the default number of iterations of the local search.
+	DefaultImproveIterations int = 100_000
+)
+
+// SetImprove enables a local search improvement pass after the build of the
+// schedule, within a number of iterations so that a seed builds the same
+// schedule again. The time limit is a safety cap, disabled when zero.
+func (s *Solver) SetImprove(iterations int, limit time.Duration) {
+	s.improveIterations = iterations
+	s.improveLimit = limit
+}
+
+// improve lowers the score of an assignment with simulated annealing, moving a
+// user to another shift or swapping the users of two shifts, while respecting
+// availabilities, eligibility rules, quotas, anti-fatigue rules and non
+// repetitive selection. Users never lose shift days below their minimum.
+func (s *Solver) improve(shifts []shift, a assignment) assignment {
+	start := time.Now()
+	current := clone(a)
+	currentScore := s.score(shifts, current, s.fallbacks).Total
+	best := clone(current)
+	bestScore := currentScore
+	initialScore := currentScore
+
+	// continued shifts are given by the previous schedule, and pinned slots by pins
+	slots := [][2]int{}
+	for k, sh := range shifts {
+		if sh.Continued {
+			continue
+		}
+		for l := range s.layers {
+			if !s.isPinned(k, l) {
+				slots = append(slots, [2]int{k, l})
+			}
+		}
+	}
+
+	if len(slots) == 0 {
+		return a
+	}
+
+	iterations := 0
+	for ; iterations < s.improveIterations; iterations++ {
+		if s.improveLimit > 0 && time.Since(start) >= s.improveLimit {
+			log.Info().Msgf("local search: time limit of %s reached, the schedule may not be built again with the same seed", s.improveLimit)
+			break
+		}
+
+		temperature := ImproveTemperature * (1 - float64(iterations)/float64(s.improveIterations))
+
+		sl := slots[s.rand.Intn(len(slots))]
+		k, l := sl[0], sl[1]
+		previous := current[k][l]
+		var k2, l2 int
+
+		if s.rand.Intn(2) == 0 {
+			// move: another user takes the shift
+			user := s.input.Users[s.rand.Intn(len(s.input.Users))]
+			u, err := s.users.RetrieveAssignedUser(user)
+			if err != nil || u == previous {
+				continue
+			}
+
+			current[k][l] = u
+			if !s.allowed(shifts, current, k, l) || s.belowMinimum(shifts, current, previous) {
+				current[k][l] = previous
+				continue
+			}
+			k2, l2 = k, l
+		} else {
+			// swap: users of two shifts exchange them
+			other := slots[s.rand.Intn(len(slots))]
+			k2, l2 = other[0], other[1]
+			if current[k2][l2] == previous {
+				continue
+			}
+
+			current[k][l], current[k2][l2] = current[k2][l2], previous
+			// the user of the longer shift loses shift days
+			lost := len(shifts[k].Days) - len(shifts[k2].Days)
+			if !s.allowed(shifts, current, k, l) || !s.allowed(shifts, current, k2, l2) ||
+				lost > 0 && s.belowMinimum(shifts, current, previous) || lost < 0 && s.belowMinimum(shifts, current, current[k][l]) {
+				current[k][l], current[k2][l2] = previous, current[k][l]
+				continue
+			}
+		}
+
+		score := s.score(shifts, current, s.fallbacks).Total
+		delta := score - currentScore
+		if delta <= 0 || (temperature > 0 && s.rand.Float64() < math.Exp(-delta/temperature)) {
+			currentScore = score
+			if score < bestScore {
+				bestScore = score
+				best = clone(current)
+			}
+			continue
+		}
+
+		// rejected, undo the move or the swap
+		if k2 == k && l2 == l {
+			current[k][l] = previous
+		} else {
+			current[k2][l2] = current[k][l]
+			current[k][l] = previous
+		}
+	}
+
+	log.Info().Msgf("local search: score improved from %.2f to %.2f in %d iterations", initialScore, bestScore, iterations)
+
+	return best
+}
+
+// allowed tells whether the user assigned to a layer of a shift satisfies the
+// hard constraints, given the users of the other shifts.
+func (s *Solver) allowed(shifts []shift, a assignment, k, l int) bool {
+	sh := shifts[k]
+	u := a[k][l]
+
+	user, ok := s.inputUser(u)
+	if !ok || s.ineligibility(l, sh, user) != "" || !sh.isAvailable(user) {
+		return false
+	}
+
+	for l2, other := range a[k] {
+		if l2 != l && other == u {
+			return false
+		}
+	}
+
+	// a user can not take two consecutive shifts of a window, nor two windows of a
+	// shift, nor two shifts following each other in time
+	windows := max(1, len(s.windows))
+	if k < windows && slices.Contains(s.lastAssignedUsers, u) {
+		return false
+	}
+
+	for j := k - k%windows; j < k-k%windows+windows && j < len(shifts); j++ {
+		if j != k && slices.Contains(a[j], u) {
+			return false
+		}
+	}
+
+	for _, j := range []int{k - windows, k + windows, k - 1, k + 1} {
+		if j >= 0 && j < len(shifts) && slices.Contains(a[j], u) {
+			return false
+		}
+	}
+
+	if s.apartFrom(u.Email, append(slices.Clone(a[k]), s.sameDay(shifts, a, k)...)) {
+		return false
+	}
+
+	if s.mentoring() {
+		emails := make([]string, len(a[k]))
+		for l2, other := range a[k] {
+			emails[l2] = other.Email
+		}
+
+		if s.unpaired(emails) {
+			return false
+		}
+	}
+
+	if s.hasRules() && s.ruleViolation(sh, user, s.assignedDuties(shifts, a, k, u)) != "" {
+		return false
+	}
+
+	if user.MaxShifts > 0 || user.MaxWeekends > 0 {
+		stats, weekends, _ := s.count(shifts, a)
+		if stats[user.Email] > user.MaxShifts && user.MaxShifts > 0 ||
+			weekends[user.Email] > user.MaxWeekends && user.MaxWeekends > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+func clone(a assignment) assignment {
+	c := make(assignment, len(a))
+	for k := range a {
+		c[k] = slices.Clone(a[k])
+	}
+
+	return c
+}
+
+// setStats sets users stats from an assignment.
+func (s *Solver) setStats(shifts []shift, a assignment) {
+	s.Stats, s.WeekendStats, s.HolidayStats = s.count(shifts, a)
+}
@@ -0,0 +1,172 @@
+package solver
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+const testImproveIterations = 3000
+
+func TestImproveHardConstraints(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     func(cfg *config.Config)
+		users   func(users []pagerduty.User)
+		n       int
+		days    int
+		newbies []string
+		pins    []config.Pin
+		pairs   []config.Pair
+	}{
+		{
+			name: "availability and eligibility",
+			cfg:  func(cfg *config.Config) { cfg.Shift.Length = config.WeekendBundled },
+			n:    6,
+			days: 14,
+			users: func(users []pagerduty.User) {
+				users[1].Unavailable = []time.Time{day(2), day(3), day(4)}
+				users[2].Unavailable = []time.Time{day(5), day(6), day(12)}
+				users[3].IfNeeded = []time.Time{day(1), day(8)}
+			},
+			newbies: []string{email(1)},
+		},
+		{
+			name: "windows",
+			cfg: func(cfg *config.Config) {
+				cfg.Windows = []config.Window{
+					{Name: "day", Start: "08:00", End: "20:00"},
+					{Name: "night", Start: "20:00", End: "08:00", Regions: []string{"apac"}},
+				}
+				cfg.Users = map[string]config.User{}
+				for i := 5; i <= 10; i++ {
+					cfg.Users[email(i)] = config.User{Region: "apac"}
+				}
+			},
+			n:    10,
+			days: 7,
+		},
+		{
+			name: "never together",
+			n:    6,
+			days: 10,
+			// preferred days would bring the pair together
+			users: func(users []pagerduty.User) {
+				users[0].Preferred = firstDays(10)
+				users[1].Preferred = firstDays(10)
+			},
+			pairs: []config.Pair{{Users: []string{email(1), email(2)}, Rule: config.NeverTogether}},
+		},
+		{
+			name: "mentoring",
+			cfg: func(cfg *config.Config) {
+				cfg.Layers[1].Mentor = true
+				cfg.Users = map[string]config.User{
+					email(1): {Mentors: []string{email(3)}},
+					email(4): {Senior: true},
+					email(5): {Senior: true},
+				}
+			},
+			n:       6,
+			days:    10,
+			newbies: []string{email(1), email(2)},
+		},
+		{
+			name: "rules",
+			cfg: func(cfg *config.Config) {
+				cfg.Shift.Length = config.WeekendBundled
+				cfg.Rules = config.Rules{MinRestDays: 1, MaxShiftsPerWeek: 2, WeekendRest: true}
+			},
+			n:    8,
+			days: 14,
+		},
+		{
+			name: "quotas",
+			cfg: func(cfg *config.Config) {
+				cfg.Shift.Length = config.WeekendBundled
+				cfg.Users = map[string]config.User{
+					email(1): {MaxShifts: 2},
+					email(2): {MaxWeekends: 1},
+					email(3): {MaxShifts: 4},
+					email(4): {MinShifts: 6},
+				}
+			},
+			n:    7,
+			days: 14,
+		},
+		{
+			name: "pins",
+			n:    6,
+			days: 10,
+			pins: []config.Pin{
+				{Date: day(1).Format(time.DateOnly), Layer: "secondary", User: email(1)},
+				{Date: day(4).Format(time.DateOnly), Layer: "primary", User: email(1)},
+				{Date: day(5).Format(time.DateOnly), Layer: "primary", User: email(2)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := func(iterations int) *Solver {
+				cfg := testConfig()
+				if tt.cfg != nil {
+					tt.cfg(&cfg)
+				}
+
+				users := team(tt.n)
+				if tt.users != nil {
+					tt.users(users)
+				}
+
+				s := newTestSolver(t, cfg, users, tt.days, tt.newbies, nil)
+				s.SetConstraints(config.Constraints{Pairs: tt.pairs})
+				if err := s.SetPins(config.Pins{Pins: tt.pins}); err != nil {
+					t.Fatal(err)
+				}
+				s.SetImprove(iterations, 0)
+
+				if _, err := s.Run(); err != nil {
+					t.Fatal(err)
+				}
+
+				return s
+			}
+
+			built, improved := run(0), run(testImproveIterations)
+
+			if v := violations(improved, improved.schedule, improved.assignment); len(v) > 0 {
+				t.Errorf("got hard constraints violations %q", v)
+			}
+
+			if improved.Score().Total > built.Score().Total {
+				t.Errorf("got score %.2f, want at most the built schedule one %.2f", improved.Score().Total, built.Score().Total)
+			}
+		})
+	}
+}
+
+func TestImproveSeed(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+
+	run := func(seed int64) assignment {
+		s := newTestSolver(t, cfg, team(6), 28, nil, nil)
+		s.SetSeed(seed)
+		s.SetImprove(testImproveIterations, 0)
+
+		if _, err := s.Run(); err != nil {
+			t.Fatal(err)
+		}
+
+		return s.assignment
+	}
+
+	a, b := run(42), run(42)
+	if !slices.EqualFunc(a, b, slices.Equal[[]pagerduty.AssignedUser]) {
+		t.Errorf("got schedules %v and %v, want the same schedule from the same seed", a, b)
+	}
+}
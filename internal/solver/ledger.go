@@ -0,0 +1,97 @@
+package solver
+
+import (
+	"math"
+)
+
+// Balance counts the shift days, week-end shifts and public holiday shifts of a user.
+type Balance struct {
+	Shifts   int `json:"shifts"`
+	Weekends int `json:"weekends"`
+	Holidays int `json:"holidays"`
+}
+
+// Ledger holds the balance of users per month ("2006-01"), to keep fairness
+// across months.
+type Ledger map[string]map[string]Balance
+
+// Record sets the balance of users for a month, replacing any previous record
+// of the same month.
+func (l Ledger) Record(month string, stats, weekends, holidays map[string]int) {
+	balances := make(map[string]Balance, len(stats))
+	for email := range stats {
+		balances[email] = Balance{
+			Shifts:   stats[email],
+			Weekends: weekends[email],
+			Holidays: holidays[email],
+		}
+	}
+
+	l[month] = balances
+}
+
+// Totals returns the balance of users over all months but the given one.
+func (l Ledger) Totals(except string) map[string]Balance {
+	totals := make(map[string]Balance)
+
+	for month, balances := range l {
+		if month == except {
+			continue
+		}
+
+		for email, b := range balances {
+			t := totals[email]
+			t.Shifts += b.Shifts
+			t.Weekends += b.Weekends
+			t.Holidays += b.Holidays
+			totals[email] = t
+		}
+	}
+
+	return totals
+}
+
+// SetLedger sets the debt of users, positive when they had more shifts than
+// the team average in previous months and negative otherwise. Users without
+// history start even with the team average.
+func (s *Solver) SetLedger(ledger Ledger) {
+	totals := ledger.Totals(s.Month())
+
+	var shifts, weekends, holidays float64
+	n := 0
+	for _, user := range s.input.Users {
+		if t, ok := totals[user.Email]; ok {
+			shifts += float64(t.Shifts)
+			weekends += float64(t.Weekends)
+			holidays += float64(t.Holidays)
+			n++
+		}
+	}
+
+	s.Debt = make(map[string]Balance, len(s.input.Users))
+	if n == 0 {
+		return
+	}
+
+	for _, user := range s.input.Users {
+		t, ok := totals[user.Email]
+		if !ok {
+			continue
+		}
+
+		s.Debt[user.Email] = Balance{
+			Shifts:   int(math.Round(float64(t.Shifts) - shifts/float64(n))),
+			Weekends: int(math.Round(float64(t.Weekends) - weekends/float64(n))),
+			Holidays: int(math.Round(float64(t.Holidays) - holidays/float64(n))),
+		}
+	}
+}
+
+// Month returns the month of the schedule, as recorded in the ledger.
+func (s *Solver) Month() string {
+	return s.input.ScheduleStart.Format("2006-01")
+}
+
+func shiftsDebt(b Balance) int   { return b.Shifts }
+func weekendsDebt(b Balance) int { return b.Weekends }
+func holidaysDebt(b Balance) int { return b.Holidays }
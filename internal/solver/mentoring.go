@@ -0,0 +1,162 @@
+package solver
+
+import (
+	"slices"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// Mentoring rejection reasons.
+const (
+	NotNewbie string = "not a newbie"
+	Unpaired  string = "no mentor on call"
+)
+
+// mentoring tells whether newbies must be paired with a mentor on call.
+func (s *Solver) mentoring() bool {
+	return slices.ContainsFunc(s.layers, func(l config.Layer) bool { return l.Mentor })
+}
+
+// mentors tells whether a user can back up a newbie: it is one of its
+// designated mentors or a senior user.
+func (s *Solver) mentors(newbie, mentor string) bool {
+	for _, user := range s.input.Users {
+		switch user.Email {
+		case newbie:
+			if slices.Contains(user.Mentors, mentor) {
+				return true
+			}
+		case mentor:
+			if user.Senior {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unpaired tells whether a newbie on call is not backed up by the users of
+// the mentor layers, given the emails of the users of the layers of a shift,
+// empty for layers not filled yet.
+func (s *Solver) unpaired(emails []string) bool {
+	for i, newbie := range emails {
+		if newbie == "" || s.layers[i].Mentor || !slices.Contains(s.newbies, newbie) {
+			continue
+		}
+
+		for j, mentor := range emails {
+			if mentor != "" && s.layers[j].Mentor && !s.mentors(newbie, mentor) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// unpairedUsers lists the users that can not take a layer of a shift, as they
+// would leave a newbie without mentor, given the users selected on the other
+// layers. Newbies are also left out when none of their possible mentors can
+// take the mentor layers not filled yet, taken users excepted.
+func (s *Solver) unpairedUsers(layer int, sh shift, selected, taken []pagerduty.AssignedUser) []string {
+	unpaired := []string{}
+	if !s.mentoring() {
+		return unpaired
+	}
+
+	emails := make([]string, len(s.layers))
+	for i, u := range selected {
+		emails[i] = u.Email
+	}
+
+	for _, user := range s.input.Users {
+		emails[layer] = user.Email
+		if s.unpaired(emails) || (!s.layers[layer].Mentor && !s.mentorAvailable(sh, user.Email, emails, taken)) {
+			unpaired = append(unpaired, user.Email)
+		}
+	}
+
+	return unpaired
+}
+
+// mentorAvailable tells whether a user can back up a newbie on every mentor
+// layer of a shift not filled yet.
+func (s *Solver) mentorAvailable(sh shift, newbie string, emails []string, taken []pagerduty.AssignedUser) bool {
+	if !slices.Contains(s.newbies, newbie) {
+		return true
+	}
+
+	for i, layer := range s.layers {
+		if !layer.Mentor || emails[i] != "" {
+			continue
+		}
+
+		excluded := s.excludedFor(i, sh)
+		available := slices.ContainsFunc(s.input.Users, func(user pagerduty.User) bool {
+			u, err := s.users.RetrieveAssignedUser(user)
+			return err == nil && user.Email != newbie && !slices.Contains(excluded, user.Email) &&
+				!slices.Contains(taken, u) && sh.isAvailable(user) && s.mentors(newbie, user.Email)
+		})
+
+		if !available {
+			return false
+		}
+	}
+
+	return true
+}
+
+// shadow fills the shadow layers of shifts with newbies available and not on
+// call during the shift, backed up by one of their mentors or a senior user
+// on call. Shadow slots without such a newbie stay empty.
+func (s *Solver) shadow(shifts []shift, a assignment) assignment {
+	shadows := make(assignment, len(shifts))
+	windows := max(1, len(s.windows))
+
+	for k, sh := range shifts {
+		shadows[k] = make([]pagerduty.AssignedUser, len(s.shadows))
+
+		// users on call during the shift, on any layer or window
+		onCall := []pagerduty.AssignedUser{}
+		for j := k - k%windows; j < k-k%windows+windows && j < len(shifts); j++ {
+			onCall = append(onCall, a[j]...)
+		}
+
+		for i, layer := range s.shadows {
+			candidates := []pagerduty.User{}
+			stats := []int{}
+
+			for _, user := range s.input.Users {
+				if s.layerIneligibility(layer, user) != "" || !sh.isAvailable(user) ||
+					(sh.window != nil && slices.Contains(s.windowExcluded[sh.Window], user.Email)) {
+					continue
+				}
+
+				u, err := s.users.RetrieveAssignedUser(user)
+				if err != nil || slices.Contains(onCall, u) || slices.Contains(shadows[k], u) {
+					continue
+				}
+
+				if !slices.ContainsFunc(a[k], func(m pagerduty.AssignedUser) bool { return s.mentors(user.Email, m.Email) }) {
+					continue
+				}
+
+				candidates = append(candidates, user)
+				stats = append(stats, s.ShadowStats[user.Email])
+			}
+
+			if len(candidates) == 0 {
+				continue
+			}
+
+			// observing shifts are balanced between newbies
+			user := candidates[minIndex(stats, s.rand)]
+			shadows[k][i], _ = s.users.RetrieveAssignedUser(user)
+			s.ShadowStats[user.Email]++
+		}
+	}
+
+	return shadows
+}
@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+)
+
+// mentoringConfig returns the test config with the secondary layer backing up
+// user1, mentored by user3, and user2, both newbies, user4 being senior.
+func mentoringConfig() config.Config {
+	cfg := testConfig()
+	cfg.Layers[1].Mentor = true
+	cfg.Users = map[string]config.User{
+		email(1): {Mentors: []string{email(3)}},
+		email(4): {Senior: true},
+	}
+
+	return cfg
+}
+
+func TestUnpaired(t *testing.T) {
+	newbies := []string{email(1), email(2)}
+
+	tests := []struct {
+		name     string
+		emails   []string
+		unpaired bool
+	}{
+		{name: "newbie with its mentor", emails: []string{email(1), email(3)}},
+		{name: "newbie with a senior user", emails: []string{email(1), email(4)}},
+		{name: "newbie without mentor designated, with a senior user", emails: []string{email(2), email(4)}},
+		{name: "newbie with a mentor of another newbie", emails: []string{email(2), email(3)}, unpaired: true},
+		{name: "newbie with a regular user", emails: []string{email(1), email(5)}, unpaired: true},
+		{name: "newbie before the mentor layer is filled", emails: []string{email(1), ""}},
+		{name: "regular users", emails: []string{email(5), email(6)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSolver(t, mentoringConfig(), team(6), 7, newbies, nil)
+
+			if got := s.unpaired(tt.emails); got != tt.unpaired {
+				t.Errorf("got unpaired %t, want %t", got, tt.unpaired)
+			}
+		})
+	}
+}
+
+func TestMentoring(t *testing.T) {
+	newbies := []string{email(1), email(2)}
+
+	for _, te := range testEngines {
+		t.Run(te.name, func(t *testing.T) {
+			s := newTestSolver(t, mentoringConfig(), team(6), 10, newbies, nil)
+			te.set(t, s)
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			onCall := 0
+			for k, users := range s.assignment {
+				newbie, mentor := users[0].Email, users[1].Email
+				if !slices.Contains(newbies, newbie) {
+					continue
+				}
+				onCall++
+
+				if !s.mentors(newbie, mentor) {
+					t.Errorf("got %s on call with %s on %s, want one of its mentors or a senior user", newbie, mentor, s.schedule[k].Start)
+				}
+			}
+
+			if onCall == 0 {
+				t.Error("got no newbie on call")
+			}
+		})
+	}
+}
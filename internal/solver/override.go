@@ -2,7 +2,6 @@ package solver
 
 import (
 	"slices"
-	"time"
 
 	"github.com/rs/zerolog/log"
 
@@ -10,21 +9,12 @@ import (
 	"github.com/jtbonhomme/goshift/internal/utils"
 )
 
-func (s *Solver) processOverride(label string, d time.Time, lastUsers []pagerduty.AssignedUser,
-	ui *pagerduty.UserIterator, isSecondary, checkStats bool) pagerduty.Override {
-	override := pagerduty.Override{
-		Start: d,
-		End:   d.Add(utils.OneDay),
-	}
-
-	weekday := d.Weekday().String()
-
-	var excludedUsers = []string{}
-
-	// newbies are not allowed to do secondary
-	if isSecondary {
-		excludedUsers = s.newbies
-	}
+// processOverride selects the first user of the iterator that can take a
+// shift, given the users on call the previous shift and the same day, users
+// available if needed being only selected when allowed.
+func (s *Solver) processOverride(label string, sh shift, lastUsers []pagerduty.AssignedUser,
+	ui *pagerduty.UserIterator, excludedUsers []string, sameDay []pagerduty.AssignedUser, checkStats, ifNeeded bool) pagerduty.AssignedUser {
+	d := sh.Start
 
 	// schedule override
 	for i := 0; i < len(s.input.Users); i++ {
@@ -32,28 +22,15 @@ func (s *Solver) processOverride(label string, d time.Time, lastUsers []pagerdut
 		// pick next user in the list
 		if !ok {
 			log.Debug().Msgf("\t%s [%s] error no result for next iterator with exclude", label, d.String())
-			return override
+			return pagerduty.AssignedUser{}
 		}
 
 		log.Debug().Msgf("\t%s [%s] considering %s: %d | %d shifts (min Shifts: %d - min Weekends: %d | avg Shifts: %d - avg Weekends: %d)",
 			label, d.String(), user.Email, s.Stats[user.Email], s.WeekendStats[user.Email], utils.Min(s.Stats),
 			utils.Min(s.WeekendStats), utils.Average(s.Stats), utils.Average(s.WeekendStats))
 
-		// if user is un available that day, move to the next user
-		if slices.Contains(user.Unavailable, d) {
-			log.Debug().Msg(" not available this day --> NEXT")
-			continue
-		}
-
-		// already too much weekend shifts for this user
-		if checkStats && weekday == time.Saturday.String() && s.WeekendStats[user.Email] > utils.Min(s.WeekendStats) {
-			log.Debug().Msg(" too much week-ends (> min) --> NEXT")
-			continue
-		}
-
-		// already too much week days shifts for this user
-		if checkStats && weekday != time.Saturday.String() && s.Stats[user.Email] > utils.Min(s.Stats) {
-			log.Debug().Msg(" stats too high (> min) --> NEXT")
+		if reason := s.rejection(sh, user, checkStats, ifNeeded); reason != "" {
+			log.Debug().Msgf(" %s --> NEXT", reason)
 			continue
 		}
 
@@ -68,12 +45,76 @@ func (s *Solver) processOverride(label string, d time.Time, lastUsers []pagerdut
 			continue
 		}
 
-		override.User = u
-		s.Stats[user.Email]++
+		if s.apartFrom(user.Email, sameDay) {
+			log.Debug().Msg(" user never together with a user of the day --> NEXT")
+			continue
+		}
+
+		s.record(sh, user)
 		log.Debug().Msg(" --> SELECTED")
 
-		break
+		return u
+	}
+
+	return pagerduty.AssignedUser{}
+}
+
+// record counts a shift taken by a user in its stats and duties.
+func (s *Solver) record(sh shift, user pagerduty.User) {
+	s.Stats[user.Email] += len(sh.Days)
+	addDuty(s.duties, user.Email, sh.Days, false)
+	if sh.isWeekendFor(user, s.weekend) {
+		s.WeekendStats[user.Email]++
+	}
+	if sh.isHolidayFor(user, s.holidays) {
+		s.HolidayStats[user.Email]++
+	}
+}
+
+// rejection returns why an eligible user can not take a shift, if so: the user
+// is unavailable (or only if needed, when not allowed), would exceed its quotas or break a rule, or already had more shifts than
+// others, ledger debt and capacity included, when stats are checked.
+func (s *Solver) rejection(sh shift, user pagerduty.User, checkStats, ifNeeded bool) string {
+	// if user is un available one day of the shift, move to the next user
+	if !sh.isAvailable(user) {
+		return Unavailable
+	}
+
+	if !ifNeeded && sh.isIfNeededFor(user) {
+		return IfNeededOnly
+	}
+
+	if reason := sh.overQuota(user, s.weekend, s.Stats[user.Email], s.WeekendStats[user.Email]); reason != "" {
+		return reason
+	}
+
+	duties := append(slices.Clip(s.duties[user.Email]), s.pinnedAfter(sh, user.Email)...)
+	if reason := s.ruleViolation(sh, user, duties); reason != "" {
+		return reason
+	}
+
+	if !checkStats {
+		return ""
+	}
+
+	// already too much public holidays shifts for this user
+	holidays := s.load(s.HolidayStats, holidaysDebt)
+	if sh.isHolidayFor(user, s.holidays) && holidays[user.Email] > utils.Min(holidays) {
+		return TooManyHolidays
+	}
+
+	// already too much weekend shifts for this user
+	weekends := s.load(s.WeekendStats, weekendsDebt)
+	if sh.isWeekendFor(user, s.weekend) && weekends[user.Email] > utils.Min(weekends) {
+		return TooManyWeekends
+	}
+
+	// already too much week days shifts for this user, unless below its minimum
+	stats := s.load(s.Stats, shiftsDebt)
+	if sh.hasWorkdaysFor(user, s.weekend, s.holidays) && stats[user.Email] > utils.Min(stats) &&
+		!underQuota(user, s.Stats[user.Email]) {
+		return StatsTooHigh
 	}
 
-	return override
+	return ""
 }
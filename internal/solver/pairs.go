@@ -0,0 +1,120 @@
+package solver
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// NeverTogetherWith is the rejection reason of a user never together with a
+// user on call the same day.
+const NeverTogetherWith string = "never together with a user on call"
+
+// PairingViolation lists the days a prefer-together pair of users was not on
+// call together.
+type PairingViolation struct {
+	Users [2]string
+	Days  []time.Time
+}
+
+// SetConstraints gives the never-together and prefer-together pairs of users.
+func (s *Solver) SetConstraints(c config.Constraints) {
+	s.apart = c.WithRule(config.NeverTogether)
+	s.together = c.WithRule(config.PreferTogether)
+}
+
+// partners returns the users paired with a user.
+func partners(pairs [][2]string, email string) []string {
+	p := []string{}
+
+	for _, pair := range pairs {
+		switch email {
+		case pair[0]:
+			p = append(p, pair[1])
+		case pair[1]:
+			p = append(p, pair[0])
+		}
+	}
+
+	return p
+}
+
+// apartFrom tells whether a user is never together with one of the users on call.
+func (s *Solver) apartFrom(email string, onCall []pagerduty.AssignedUser) bool {
+	for _, p := range partners(s.apart, email) {
+		if slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == p }) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// preferredPartners returns the users preferably on call with the users on
+// call, in input order.
+func (s *Solver) preferredPartners(onCall []pagerduty.AssignedUser) []pagerduty.User {
+	emails := []string{}
+	for _, u := range onCall {
+		emails = append(emails, partners(s.together, u.Email)...)
+	}
+
+	preferred := []pagerduty.User{}
+	for _, user := range s.input.Users {
+		if slices.Contains(emails, user.Email) {
+			preferred = append(preferred, user)
+		}
+	}
+
+	return preferred
+}
+
+// sameDay returns the users on call during the other windows of a shift, on
+// any layer.
+func (s *Solver) sameDay(shifts []shift, a assignment, k int) []pagerduty.AssignedUser {
+	windows := max(1, len(s.windows))
+	users := []pagerduty.AssignedUser{}
+
+	for j := k - k%windows; j < k-k%windows+windows && j < len(shifts); j++ {
+		if j != k {
+			users = append(users, a[j]...)
+		}
+	}
+
+	return users
+}
+
+// PairingViolations lists the prefer-together pairs of users apart in the
+// schedule built by the last run.
+func (s *Solver) PairingViolations() []PairingViolation {
+	return s.pairingViolations(s.schedule, s.assignment)
+}
+
+func (s *Solver) pairingViolations(shifts []shift, a assignment) []PairingViolation {
+	violations := []PairingViolation{}
+	windows := max(1, len(s.windows))
+
+	for _, pair := range s.together {
+		v := PairingViolation{Users: pair}
+
+		for k := 0; k < len(shifts); k += windows {
+			if shifts[k].Continued {
+				continue
+			}
+
+			onCall := append(slices.Clone(a[k]), s.sameDay(shifts, a, k)...)
+			first := slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == pair[0] })
+			second := slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == pair[1] })
+			if first != second {
+				v.Days = append(v.Days, shifts[k].Days[0])
+			}
+		}
+
+		if len(v.Days) > 0 {
+			violations = append(violations, v)
+		}
+	}
+
+	return violations
+}
@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestApartFrom(t *testing.T) {
+	s := newTestSolver(t, testConfig(), team(4), 7, nil, nil)
+	s.SetConstraints(config.Constraints{Pairs: []config.Pair{
+		{Users: []string{email(1), email(2)}, Rule: config.NeverTogether},
+		{Users: []string{email(1), email(3)}, Rule: config.PreferTogether},
+	}})
+
+	tests := []struct {
+		name   string
+		email  string
+		onCall []int
+		apart  bool
+	}{
+		{name: "first of the pair", email: email(1), onCall: []int{2}, apart: true},
+		{name: "second of the pair", email: email(2), onCall: []int{4, 1}, apart: true},
+		{name: "other users", email: email(1), onCall: []int{3, 4}},
+		{name: "no user on call", email: email(2)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			onCall := []pagerduty.AssignedUser{}
+			for _, i := range tt.onCall {
+				onCall = append(onCall, pagerduty.AssignedUser{Email: email(i)})
+			}
+
+			if got := s.apartFrom(tt.email, onCall); got != tt.apart {
+				t.Errorf("got apart %t, want %t", got, tt.apart)
+			}
+		})
+	}
+}
+
+func TestNeverTogether(t *testing.T) {
+	windows := func(cfg *config.Config) {
+		cfg.Windows = []config.Window{
+			{Name: "day", Start: "08:00", End: "20:00"},
+			{Name: "night", Start: "20:00", End: "08:00"},
+		}
+	}
+
+	tests := []struct {
+		name string
+		cfg  func(cfg *config.Config)
+		n    int
+	}{
+		{name: "layers", n: 6},
+		{name: "windows", cfg: windows, n: 10},
+	}
+
+	for _, tt := range tests {
+		for _, te := range testEngines {
+			t.Run(tt.name+" "+te.name, func(t *testing.T) {
+				cfg := testConfig()
+				if tt.cfg != nil {
+					tt.cfg(&cfg)
+				}
+
+				// preferred days would bring the pair together
+				users := team(tt.n)
+				users[0].Preferred = firstDays(7)
+				users[1].Preferred = firstDays(7)
+
+				s := newTestSolver(t, cfg, users, 7, nil, nil)
+				s.SetConstraints(config.Constraints{Pairs: []config.Pair{{Users: []string{email(1), email(2)}, Rule: config.NeverTogether}}})
+				te.set(t, s)
+
+				if _, err := s.Run(); err != nil {
+					t.Fatal(err)
+				}
+
+				for k, sh := range s.schedule {
+					onCall := append(slices.Clone(s.assignment[k]), s.sameDay(s.schedule, s.assignment, k)...)
+					first := slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == email(1) })
+					second := slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == email(2) })
+					if first && second {
+						t.Errorf("got %s and %s on call together on %s", email(1), email(2), sh.Start)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestPairingViolations(t *testing.T) {
+	s := newTestSolver(t, testConfig(), team(4), 3, nil, nil)
+	s.SetConstraints(config.Constraints{Pairs: []config.Pair{{Users: []string{email(1), email(2)}, Rule: config.PreferTogether}}})
+
+	shifts := s.shifts()
+	u := func(i int) pagerduty.AssignedUser { return pagerduty.AssignedUser{Email: email(i)} }
+	a := assignment{{u(1), u(2)}, {u(3), u(1)}, {u(3), u(4)}}
+
+	// the pair is apart the second day only, being off call together the third day
+	want := []PairingViolation{{Users: [2]string{email(1), email(2)}, Days: []time.Time{day(1)}}}
+	got := s.pairingViolations(shifts, a)
+	if len(got) != 1 || got[0].Users != want[0].Users || !slices.Equal(got[0].Days, want[0].Days) {
+		t.Errorf("got violations %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package solver
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// SetPins gives the agreed-upon assignments of users to layers, set before
+// solving and never changed. Every pin must match a shift of the schedule not
+// started in the previous schedule, an on-call layer the user is eligible for
+// and a user available during the shift, and a user can not be pinned to
+// consecutive shifts.
+func (s *Solver) SetPins(pins config.Pins) error {
+	shifts := s.shifts()
+	pinned := make(assignment, len(shifts))
+	for k := range pinned {
+		pinned[k] = make([]pagerduty.AssignedUser, len(s.layers))
+	}
+
+	for _, p := range pins.Pins {
+		l := slices.IndexFunc(s.layers, func(layer config.Layer) bool { return layer.Name == p.Layer })
+		if l < 0 {
+			return fmt.Errorf("unknown on-call layer %s pinned on %s", p.Layer, p.Date)
+		}
+
+		if len(s.windows) > 0 && !slices.ContainsFunc(s.windows, func(w config.Window) bool { return w.Name == p.Window }) {
+			return fmt.Errorf("unknown window %q pinned on %s", p.Window, p.Date)
+		}
+
+		i := slices.IndexFunc(s.input.Users, func(user pagerduty.User) bool { return user.Email == p.User })
+		if i < 0 {
+			return fmt.Errorf("pinned user %s has no availabilities", p.User)
+		}
+
+		u, err := s.users.RetrieveAssignedUserByEmail(p.User)
+		if err != nil {
+			return err
+		}
+
+		k := slices.IndexFunc(shifts, func(sh shift) bool { return sh.isPinnedBy(p) })
+		switch {
+		case k < 0:
+			return fmt.Errorf("pin of %s on %s out of the schedule", p.User, p.Date)
+		case shifts[k].Continued:
+			return fmt.Errorf("pin of %s on %s in a shift started in the previous schedule", p.User, p.Date)
+		case pinned[k][l].Email != "" && pinned[k][l] != u:
+			return fmt.Errorf("%s and %s both pinned to %s on %s", pinned[k][l].Email, p.User, p.Layer, p.Date)
+		}
+
+		if reason := s.ineligibility(l, shifts[k], s.input.Users[i]); reason != "" {
+			return fmt.Errorf("%s pinned to %s on %s while not eligible: %s", p.User, p.Layer, p.Date, reason)
+		}
+
+		if !shifts[k].isAvailable(s.input.Users[i]) {
+			return fmt.Errorf("%s pinned to %s on %s while unavailable", p.User, p.Layer, p.Date)
+		}
+
+		pinned[k][l] = u
+		for i, other := range pinned[k] {
+			if i != l && other == u {
+				return fmt.Errorf("%s pinned to %s and %s on %s", p.User, s.layers[i].Name, p.Layer, p.Date)
+			}
+		}
+	}
+
+	if err := s.consecutivePins(shifts, pinned); err != nil {
+		return err
+	}
+
+	s.pins = pins.Pins
+
+	return nil
+}
+
+// consecutivePins returns an error when a user is pinned to two shifts it can
+// not both take: two windows of a day, two consecutive shifts of a window or
+// two shifts following each other in time.
+func (s *Solver) consecutivePins(shifts []shift, pinned assignment) error {
+	windows := max(1, len(s.windows))
+
+	for k := range pinned {
+		next := []int{k + windows, k + 1}
+		for j := k + 1; j < k-k%windows+windows; j++ {
+			next = append(next, j)
+		}
+
+		for _, u := range pinned[k] {
+			if u.Email == "" {
+				continue
+			}
+
+			for _, j := range next {
+				if j < len(pinned) && slices.Contains(pinned[j], u) {
+					return fmt.Errorf("%s pinned to consecutive shifts from %s and %s", u.Email,
+						shifts[k].Start.Format(time.DateOnly), shifts[j].Start.Format(time.DateOnly))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// isPinnedBy tells whether a pin applies to the shift.
+func (sh shift) isPinnedBy(p config.Pin) bool {
+	if sh.window != nil && sh.window.Name != p.Window {
+		return false
+	}
+
+	return slices.ContainsFunc(sh.Days, func(d time.Time) bool { return d.Format(time.DateOnly) == p.Date })
+}
+
+// pin sets the users pinned to the layers of shifts, empty when not pinned,
+// and the days of their pinned shifts.
+func (s *Solver) pin(shifts []shift) {
+	pinned := make(assignment, len(shifts))
+	s.pinnedDuties = make(map[string][][]time.Time)
+
+	for k, sh := range shifts {
+		pinned[k] = make([]pagerduty.AssignedUser, len(s.layers))
+
+		for _, p := range s.pins {
+			if !sh.isPinnedBy(p) {
+				continue
+			}
+
+			l := slices.IndexFunc(s.layers, func(layer config.Layer) bool { return layer.Name == p.Layer })
+			pinned[k][l], _ = s.users.RetrieveAssignedUserByEmail(p.User)
+			s.pinnedDuties[p.User] = append(s.pinnedDuties[p.User], sh.Days)
+		}
+	}
+
+	s.pinning = pinned
+}
+
+// isPinned tells whether a layer of a shift is pinned.
+func (s *Solver) isPinned(k, l int) bool {
+	return s.pinning[k][l].Email != ""
+}
+
+// pinnedNear lists the users pinned to a shift, to the other windows of its
+// day, to the next shift of its window and to the next shift in time, which
+// can not take the shift.
+func (s *Solver) pinnedNear(k int) []string {
+	windows := max(1, len(s.windows))
+	emails := []string{}
+
+	add := func(j int) {
+		for _, u := range s.pinning[j] {
+			if u.Email != "" {
+				emails = append(emails, u.Email)
+			}
+		}
+	}
+
+	for j := k - k%windows; j < k-k%windows+windows && j < len(s.pinning); j++ {
+		add(j)
+	}
+	if k+windows < len(s.pinning) {
+		add(k + windows)
+	}
+	if k+1 < len(s.pinning) {
+		add(k + 1)
+	}
+
+	return emails
+}
+
+// pinnedAfter returns the days of the shifts a user is pinned to after a
+// shift, taken into account by anti-fatigue rules before being reached.
+func (s *Solver) pinnedAfter(sh shift, email string) [][]time.Time {
+	duties := [][]time.Time{}
+	last := sh.Days[len(sh.Days)-1]
+
+	for _, days := range s.pinnedDuties[email] {
+		if days[0].After(last) {
+			duties = append(duties, days)
+		}
+	}
+
+	return duties
+}
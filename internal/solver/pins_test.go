@@ -0,0 +1,104 @@
+package solver
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// pin returns the pin of a user to a layer the i-th day of test schedules.
+func pin(i int, layer string, user int) config.Pin {
+	return config.Pin{Date: day(i).Format(time.DateOnly), Layer: layer, User: email(user)}
+}
+
+func TestSetPins(t *testing.T) {
+	tests := []struct {
+		name string
+		pins []config.Pin
+		// err is part of the expected error, none when empty
+		err string
+	}{
+		{name: "no pin"},
+		{name: "pins", pins: []config.Pin{pin(1, "primary", 1), pin(1, "secondary", 2), pin(3, "primary", 1)}},
+		{name: "same pin twice", pins: []config.Pin{pin(1, "primary", 1), pin(1, "primary", 1)}},
+		{name: "unknown layer", pins: []config.Pin{pin(1, "tertiary", 1)}, err: "unknown on-call layer"},
+		{name: "user without availabilities", pins: []config.Pin{pin(1, "primary", 7)}, err: "has no availabilities"},
+		{name: "out of the schedule", pins: []config.Pin{pin(7, "primary", 1)}, err: "out of the schedule"},
+		{name: "unavailable day", pins: []config.Pin{pin(2, "primary", 3)}, err: "while unavailable"},
+		{name: "ineligible layer", pins: []config.Pin{pin(1, "secondary", 4)}, err: "while not eligible: " + Newbie},
+		{name: "two users on a layer", pins: []config.Pin{pin(1, "primary", 1), pin(1, "primary", 2)}, err: "both pinned"},
+		{name: "a user on two layers", pins: []config.Pin{pin(1, "primary", 1), pin(1, "secondary", 1)}, err: "pinned to primary and secondary"},
+		{name: "a user on consecutive shifts", pins: []config.Pin{pin(1, "primary", 1), pin(2, "secondary", 1)}, err: "consecutive shifts"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// user4 is a newbie, not eligible for the secondary layer, and user7 is known
+			// in PagerDuty only
+			users := team(6)
+			users[2].Unavailable = []time.Time{day(2)}
+
+			s := newTestSolver(t, testConfig(), users, 7, []string{email(4)}, nil)
+			s.users.Users = team(7)
+
+			err := s.SetPins(config.Pins{Pins: tt.pins})
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("got error %v, want none", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Errorf("got error %v, want an error with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSetPinsContinuedShift(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+
+	// a schedule starting on Sunday continues the Saturday shift
+	s := newTestSolver(t, cfg, team(4), 3, nil, nil)
+	s.input.ScheduleStart = day(-1)
+
+	err := s.SetPins(config.Pins{Pins: []config.Pin{pin(-1, "primary", 1)}})
+	if err == nil || !strings.Contains(err.Error(), "started in the previous schedule") {
+		t.Errorf("got error %v, want the pin in the continued shift rejected", err)
+	}
+}
+
+func TestPinsHonoured(t *testing.T) {
+	pins := []config.Pin{pin(1, "secondary", 1), pin(3, "primary", 2), pin(4, "secondary", 3)}
+
+	for _, te := range testEngines {
+		t.Run(te.name, func(t *testing.T) {
+			s := newTestSolver(t, testConfig(), team(6), 7, nil, nil)
+			te.set(t, s)
+			if err := s.SetPins(config.Pins{Pins: pins}); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			want := map[[2]int]pagerduty.AssignedUser{{1, 1}: {Email: email(1)}, {3, 0}: {Email: email(2)}, {4, 1}: {Email: email(3)}}
+			for kl, u := range want {
+				if got := s.assignment[kl[0]][kl[1]]; got.Email != u.Email {
+					t.Errorf("got %s on layer %d on %s, want the pinned %s", got.Email, kl[1], s.schedule[kl[0]].Start, u.Email)
+				}
+			}
+
+			if v := violations(s, s.schedule, s.assignment); len(v) > 0 {
+				t.Errorf("got hard constraints violations %q", v)
+			}
+
+			// pinned shifts count in stats
+			if s.Stats[email(1)] < 1 || s.Stats[email(2)] < 1 || s.Stats[email(3)] < 1 {
+				t.Errorf("got stats %v, want pinned shifts counted", s.Stats)
+			}
+		})
+	}
+}
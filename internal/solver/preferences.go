@@ -0,0 +1,86 @@
+package solver
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// PreferenceStats counts the shifts a user would rather be on call, and the
+// ones it got.
+type PreferenceStats struct {
+	Preferred int
+	Satisfied int
+}
+
+// isPreferredBy tells whether the user would rather be on call one day of the shift.
+func (sh shift) isPreferredBy(user pagerduty.User) bool {
+	return slices.ContainsFunc(sh.Days, func(day time.Time) bool { return user.Prefers(day) })
+}
+
+// preferredFirst moves the users preferring a shift first, keeping the order
+// of ranked users otherwise.
+func preferredFirst(sh shift, users []pagerduty.User) []pagerduty.User {
+	sorted := make([]pagerduty.User, 0, len(users))
+
+	for _, user := range users {
+		if sh.isPreferredBy(user) {
+			sorted = append(sorted, user)
+		}
+	}
+
+	for _, user := range users {
+		if !sh.isPreferredBy(user) {
+			sorted = append(sorted, user)
+		}
+	}
+
+	return sorted
+}
+
+// Preferences counts, for every user, the shifts of the schedule built by the
+// last run it would rather be on call, and the ones it got.
+func (s *Solver) Preferences() map[string]PreferenceStats {
+	return s.preferences(s.schedule, s.assignment)
+}
+
+// preferences counts, for every user, the shifts it would rather be on call,
+// and the ones it got on any layer or window, shifts continued from the
+// previous schedule excepted.
+func (s *Solver) preferences(shifts []shift, a assignment) map[string]PreferenceStats {
+	preferences := make(map[string]PreferenceStats, len(s.input.Users))
+	windows := max(1, len(s.windows))
+
+	for k := 0; k < len(shifts); k += windows {
+		if shifts[k].Continued {
+			continue
+		}
+
+		onCall := append(slices.Clone(a[k]), s.sameDay(shifts, a, k)...)
+		for _, user := range s.input.Users {
+			if !shifts[k].isPreferredBy(user) {
+				continue
+			}
+
+			p := preferences[user.Email]
+			p.Preferred++
+			if slices.ContainsFunc(onCall, func(u pagerduty.AssignedUser) bool { return u.Email == user.Email }) {
+				p.Satisfied++
+			}
+			preferences[user.Email] = p
+		}
+	}
+
+	return preferences
+}
+
+// missedPreferences counts the shifts users would rather be on call but did not get.
+func (s *Solver) missedPreferences(shifts []shift, a assignment) int {
+	n := 0
+	for _, p := range s.preferences(shifts, a) {
+		n += p.Preferred - p.Satisfied
+	}
+
+	return n
+}
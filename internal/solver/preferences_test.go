@@ -0,0 +1,93 @@
+package solver
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestPreferredFirst(t *testing.T) {
+	sh := shift{Days: []time.Time{day(2)}}
+
+	tests := []struct {
+		name  string
+		users func(users []pagerduty.User)
+		want  []string
+	}{
+		{name: "no preference", want: []string{email(1), email(2), email(3), email(4)}},
+		{
+			name:  "preferred date",
+			users: func(users []pagerduty.User) { users[2].Preferred = []time.Time{day(2)} },
+			want:  []string{email(3), email(1), email(2), email(4)},
+		},
+		{
+			name: "preferred date and weekday, in ranking order",
+			users: func(users []pagerduty.User) {
+				users[1].PreferredWeekdays = []time.Weekday{time.Wednesday}
+				users[3].Preferred = []time.Time{day(2)}
+			},
+			want: []string{email(2), email(4), email(1), email(3)},
+		},
+		{
+			name:  "other date",
+			users: func(users []pagerduty.User) { users[3].Preferred = []time.Time{day(3)} },
+			want:  []string{email(1), email(2), email(3), email(4)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := team(4)
+			if tt.users != nil {
+				tt.users(users)
+			}
+
+			got := []string{}
+			for _, user := range preferredFirst(sh, users) {
+				got = append(got, user.Email)
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPreferredDates(t *testing.T) {
+	for _, te := range testEngines {
+		t.Run(te.name, func(t *testing.T) {
+			// users are alike but for their preferred dates
+			users := team(6)
+			users[0].Preferred = []time.Time{day(0)}
+			users[3].Preferred = []time.Time{day(2)}
+			users[5].PreferredWeekdays = []time.Weekday{time.Friday}
+
+			s := newTestSolver(t, testConfig(), users, 5, nil, nil)
+			te.set(t, s)
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			// users and the day they prefer
+			for i, k := range map[int]int{1: 0, 4: 2, 6: 4} {
+				p := s.Preferences()[email(i)]
+				if p.Preferred == 1 && p.Satisfied == 1 {
+					continue
+				}
+
+				// the greedy engine does not look ahead, a user on call the day
+				// before its preferred day can not take it
+				before := k > 0 && slices.ContainsFunc(s.assignment[k-1], func(u pagerduty.AssignedUser) bool { return u.Email == email(i) })
+				if te.engine == Greedy && te.iterations == 0 && before {
+					continue
+				}
+
+				t.Errorf("got %d preferred shifts of %s satisfied out of %d, want 1 out of 1", p.Satisfied, email(i), p.Preferred)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"math"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// Quota rejection reasons.
+const (
+	MaxShiftsReached   string = "max shifts reached"
+	MaxWeekendsReached string = "max week-ends reached"
+)
+
+// capacity returns the share of a full-time on-call load of a user.
+func capacity(user pagerduty.User) float64 {
+	if user.Capacity <= 0 {
+		return 1
+	}
+
+	return user.Capacity
+}
+
+// load returns counts of users added to their ledger debt, relative to their
+// capacity: a part-time user at 50% with 2 shifts is as loaded as a full-time
+// user with 4 shifts.
+func (s *Solver) load(counts map[string]int, debt func(Balance) int) map[string]float64 {
+	load := make(map[string]float64, len(counts))
+
+	for _, user := range s.input.Users {
+		n, ok := counts[user.Email]
+		if !ok {
+			continue
+		}
+		load[user.Email] = float64(n+debt(s.Debt[user.Email])) / capacity(user)
+	}
+
+	return load
+}
+
+// percent rounds loads to integer percents.
+func percent(load map[string]float64) map[string]int {
+	p := make(map[string]int, len(load))
+	for email, l := range load {
+		p[email] = int(math.Round(l * 100)) //nolint:gomnd // percent
+	}
+
+	return p
+}
+
+// overQuota returns the quota a user would exceed with a shift, if any, given
+// its shift days and week-end shifts.
+func (sh shift) overQuota(user pagerduty.User, weekend pagerduty.Weekend, shifts, weekends int) string {
+	if user.MaxShifts > 0 && shifts+len(sh.Days) > user.MaxShifts {
+		return MaxShiftsReached
+	}
+
+	if user.MaxWeekends > 0 && sh.isWeekendFor(user, weekend) && weekends >= user.MaxWeekends {
+		return MaxWeekendsReached
+	}
+
+	return ""
+}
+
+// belowMinimum tells whether a user assigned to shifts does not reach its
+// minimum shift days.
+func (s *Solver) belowMinimum(shifts []shift, a assignment, u pagerduty.AssignedUser) bool {
+	user, ok := s.inputUser(u)
+	if !ok || user.MinShifts == 0 {
+		return false
+	}
+
+	stats, _, _ := s.count(shifts, a)
+
+	return underQuota(user, stats[user.Email])
+}
+
+// underQuota tells whether a user did not reach its minimum shift days.
+func underQuota(user pagerduty.User, shifts int) bool {
+	return user.MinShifts > 0 && shifts < user.MinShifts
+}
@@ -0,0 +1,98 @@
+package solver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity float64
+		want     float64
+	}{
+		{name: "not set", want: 1},
+		{name: "part-time", capacity: 0.5, want: 0.5},
+		{name: "full-time", capacity: 1, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := capacity(pagerduty.User{Capacity: tt.capacity}); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOverQuota(t *testing.T) {
+	weekend := pagerduty.DefaultWeekend()
+	saturday := shift{Days: []time.Time{day(5), day(6)}}
+	monday := shift{Days: []time.Time{day(7)}}
+
+	tests := []struct {
+		name     string
+		sh       shift
+		user     pagerduty.User
+		shifts   int
+		weekends int
+		want     string
+	}{
+		{name: "no quota", sh: saturday, shifts: 10, weekends: 3},
+		{name: "below max shifts", sh: saturday, user: pagerduty.User{MaxShifts: 4}, shifts: 2},
+		{name: "shift days above max shifts", sh: saturday, user: pagerduty.User{MaxShifts: 3}, shifts: 2, want: MaxShiftsReached},
+		{name: "max week-ends reached", sh: saturday, user: pagerduty.User{MaxWeekends: 1}, weekends: 1, want: MaxWeekendsReached},
+		{name: "max week-ends reached on a workday", sh: monday, user: pagerduty.User{MaxWeekends: 1}, weekends: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sh.overQuota(tt.user, weekend, tt.shifts, tt.weekends); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuotas(t *testing.T) {
+	half := 0.5
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+	cfg.Users = map[string]config.User{
+		email(1): {MaxShifts: 2},
+		email(2): {MaxWeekends: 1},
+		email(3): {Capacity: &half},
+		email(4): {MinShifts: 4},
+	}
+
+	for _, engine := range []string{Greedy, Exact} {
+		t.Run(engine, func(t *testing.T) {
+			s := newTestSolver(t, cfg, team(6), 14, nil, nil)
+			if err := s.SetEngine(engine); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := violations(s, s.schedule, s.assignment); len(v) > 0 {
+				t.Errorf("got hard constraints violations %q", v)
+			}
+
+			if s.Stats[email(4)] < 4 {
+				t.Errorf("got %d shift days for %s, want its minimum 4", s.Stats[email(4)], email(4))
+			}
+
+			// the part-time user takes less shift days than full-time ones
+			for _, i := range []int{5, 6} {
+				if s.Stats[email(3)] >= s.Stats[email(i)] {
+					t.Errorf("got %d shift days for the part-time user, want less than the %d ones of %s", s.Stats[email(3)], s.Stats[email(i)], email(i))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package solver
+
+import (
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// Anti-fatigue rules rejection reasons.
+const (
+	RestTooShort     string = "rest period too short"
+	TooManyShiftWeek string = "too many shifts in 7 days"
+	NextToWeekend    string = "too close to a week-end shift"
+)
+
+// SetHistory gives the days users were on-call before the schedule start, on
+// any layer, so that rules apply across the month boundary.
+func (s *Solver) SetHistory(history map[string][]time.Time) {
+	s.history = make(map[string][][]time.Time, len(history))
+
+	for email, days := range history {
+		days = slices.Clone(days)
+		slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+
+		for _, d := range days {
+			addDuty(s.history, email, []time.Time{d}, true)
+		}
+	}
+}
+
+// addDuty records the days of a shift taken by a user, merged with its last
+// shift when they follow it.
+func addDuty(duties map[string][][]time.Time, email string, days []time.Time, merge bool) {
+	d := duties[email]
+	if n := len(d); merge && n > 0 && dayDistance(d[n-1][len(d[n-1])-1], days[0]) == 1 {
+		d[n-1] = append(slices.Clip(d[n-1]), days...)
+		return
+	}
+
+	duties[email] = append(slices.Clip(d), slices.Clone(days))
+}
+
+// ruleViolation returns the rule a user would break by taking a shift, if
+// any, given the days of its other shifts.
+func (s *Solver) ruleViolation(sh shift, user pagerduty.User, duties [][]time.Time) string {
+	first, last := sh.Days[0], sh.Days[len(sh.Days)-1]
+	weekend := sh.isWeekendFor(user, s.weekend)
+
+	for _, duty := range duties {
+		// days off between the two shifts, negative when they overlap
+		gap := dayDistance(duty[len(duty)-1], first) - 1
+		if duty[0].After(last) {
+			gap = dayDistance(last, duty[0]) - 1
+		}
+
+		if s.rules.MinRestDays > 0 && gap < s.rules.MinRestDays {
+			return RestTooShort
+		}
+
+		// week-end shifts are followed and preceded by a rest day at least
+		if s.rules.WeekendRest && gap <= 1 && weekend != s.isWeekendDuty(duty, user) {
+			return NextToWeekend
+		}
+	}
+
+	if s.rules.MaxShiftsPerWeek > 0 {
+		// every rolling 7 days holding the shift start
+		for w := -6; w <= 0; w++ {
+			n := 1
+			for _, duty := range duties {
+				if d := dayDistance(first, duty[0]); d >= w && d <= w+6 {
+					n++
+				}
+			}
+
+			if n > s.rules.MaxShiftsPerWeek {
+				return TooManyShiftWeek
+			}
+		}
+	}
+
+	return ""
+}
+
+// isWeekendDuty tells whether shift days hold a week-end day of the user.
+func (s *Solver) isWeekendDuty(days []time.Time, user pagerduty.User) bool {
+	return slices.ContainsFunc(days, func(d time.Time) bool { return user.IsWeekend(d, s.weekend) })
+}
+
+// hasRules tells whether anti-fatigue rules are set.
+func (s *Solver) hasRules() bool {
+	return s.rules.MinRestDays > 0 || s.rules.MaxShiftsPerWeek > 0 || s.rules.WeekendRest
+}
+
+// assignedDuties returns the days of the shifts of a user in an assignment,
+// but the given one, within the rules lookback, history included.
+func (s *Solver) assignedDuties(shifts []shift, a assignment, k int, u pagerduty.AssignedUser) [][]time.Time {
+	duties := slices.Clone(s.history[u.Email])
+	from := shifts[k].Days[0].Add(-time.Duration(s.rules.Lookback()) * utils.OneDay)
+
+	for j, sh := range shifts {
+		if j == k || sh.Days[len(sh.Days)-1].Before(from) || !slices.Contains(a[j], u) {
+			continue
+		}
+		duties = append(duties, sh.Days)
+	}
+
+	return duties
+}
+
+// dayDistance returns the number of days from a to b.
+func dayDistance(a, b time.Time) int {
+	return int(math.Round(b.Sub(a).Hours() / 24)) //nolint:gomnd // hours of a day
+}
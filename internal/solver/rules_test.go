@@ -0,0 +1,194 @@
+package solver
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestDayDistance(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		a, b time.Time
+		want int
+	}{
+		{name: "same day", a: day(0), b: day(0)},
+		{name: "next day", a: day(0), b: day(1), want: 1},
+		{name: "previous day", a: day(1), b: day(0), want: -1},
+		{name: "across months", a: day(-3), b: day(2), want: 5},
+		{
+			name: "over a 25 hours day",
+			a:    time.Date(2024, 10, 26, 9, 0, 0, 0, paris),
+			b:    time.Date(2024, 10, 28, 9, 0, 0, 0, paris),
+			want: 2,
+		},
+		{
+			name: "over a 23 hours day",
+			a:    time.Date(2024, 3, 30, 9, 0, 0, 0, paris),
+			b:    time.Date(2024, 3, 31, 9, 0, 0, 0, paris),
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dayDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRuleViolation(t *testing.T) {
+	// monday is day 0, the week-end days 5 and 6 and the next monday day 7
+	weekend := []time.Time{day(5), day(6)}
+
+	tests := []struct {
+		name   string
+		rules  config.Rules
+		days   []time.Time
+		duties [][]time.Time
+		want   string
+	}{
+		{name: "no rule", days: []time.Time{day(3)}, duties: [][]time.Time{{day(2)}, {day(4)}}},
+		{name: "rest after a shift", rules: config.Rules{MinRestDays: 2}, days: []time.Time{day(3)}, duties: [][]time.Time{{day(0)}}},
+		{name: "rest too short after a shift", rules: config.Rules{MinRestDays: 2}, days: []time.Time{day(3)}, duties: [][]time.Time{{day(1)}}, want: RestTooShort},
+		{name: "rest too short before a shift", rules: config.Rules{MinRestDays: 2}, days: []time.Time{day(3)}, duties: [][]time.Time{{day(5)}}, want: RestTooShort},
+		{name: "rest after a week-end", rules: config.Rules{MinRestDays: 1}, days: []time.Time{day(8)}, duties: [][]time.Time{weekend}},
+		{name: "rest too short after a week-end last day", rules: config.Rules{MinRestDays: 2}, days: []time.Time{day(8)}, duties: [][]time.Time{weekend}, want: RestTooShort},
+		{name: "rest too short before a week-end", rules: config.Rules{MinRestDays: 1}, days: weekend, duties: [][]time.Time{{day(4)}}, want: RestTooShort},
+		{name: "workday two days before a week-end", rules: config.Rules{WeekendRest: true}, days: []time.Time{day(3)}, duties: [][]time.Time{weekend}, want: NextToWeekend},
+		{name: "workday two days after a week-end", rules: config.Rules{WeekendRest: true}, days: []time.Time{day(8)}, duties: [][]time.Time{weekend}, want: NextToWeekend},
+		{name: "workday three days after a week-end", rules: config.Rules{WeekendRest: true}, days: []time.Time{day(9)}, duties: [][]time.Time{weekend}},
+		{name: "week-end two days after a workday", rules: config.Rules{WeekendRest: true}, days: weekend, duties: [][]time.Time{{day(3)}}, want: NextToWeekend},
+		{name: "two shifts in 7 days", rules: config.Rules{MaxShiftsPerWeek: 2}, days: []time.Time{day(6)}, duties: [][]time.Time{{day(0)}}},
+		{
+			name:   "three shifts in 7 days",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(6)},
+			duties: [][]time.Time{{day(0)}, {day(3)}},
+			want:   TooManyShiftWeek,
+		},
+		{
+			name:   "three shifts in 8 days",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(7)},
+			duties: [][]time.Time{{day(0)}, {day(3)}},
+		},
+		{
+			name:   "three shifts in 7 days, the shift first",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(0)},
+			duties: [][]time.Time{{day(3)}, {day(6)}},
+			want:   TooManyShiftWeek,
+		},
+		{
+			name:   "three shifts in 7 days, the shift in the middle",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(3)},
+			duties: [][]time.Time{{day(0)}, {day(6)}},
+			want:   TooManyShiftWeek,
+		},
+		{
+			name:   "week-end counting once",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(9)},
+			duties: [][]time.Time{weekend},
+		},
+		{
+			name:   "week-end starting 7 days before",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(12)},
+			duties: [][]time.Time{weekend, {day(9)}},
+		},
+		{
+			name:   "week-end starting 6 days before",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(11)},
+			duties: [][]time.Time{weekend, {day(9)}},
+			want:   TooManyShiftWeek,
+		},
+		{
+			name:   "week-end third shift in 7 days",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   weekend,
+			duties: [][]time.Time{{day(0)}, {day(2)}},
+			want:   TooManyShiftWeek,
+		},
+		{
+			name:   "history days before the month",
+			rules:  config.Rules{MaxShiftsPerWeek: 2},
+			days:   []time.Time{day(1)},
+			duties: [][]time.Time{{day(-4)}, {day(-1)}},
+			want:   TooManyShiftWeek,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSolver(t, testConfig(), team(1), 14, nil, nil)
+			s.rules = tt.rules
+
+			if got := s.ruleViolation(shift{Days: tt.days}, s.input.Users[0], tt.duties); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetHistory(t *testing.T) {
+	s := newTestSolver(t, testConfig(), team(1), 14, nil, nil)
+	s.SetHistory(map[string][]time.Time{email(1): {day(-1), day(-5), day(-2)}})
+
+	// following days are merged into one shift
+	want := [][]time.Time{{day(-5)}, {day(-2), day(-1)}}
+	if got := s.history[email(1)]; !slices.EqualFunc(got, want, slices.Equal[[]time.Time]) {
+		t.Errorf("got history %v, want %v", got, want)
+	}
+}
+
+func TestRulesAtMonthStart(t *testing.T) {
+	cfg := testConfig()
+	cfg.Rules = config.Rules{MinRestDays: 1, MaxShiftsPerWeek: 2}
+
+	for _, engine := range []string{Greedy, Exact} {
+		t.Run(engine, func(t *testing.T) {
+			s := newTestSolver(t, cfg, team(6), 5, nil, nil)
+			if err := s.SetEngine(engine); err != nil {
+				t.Fatal(err)
+			}
+
+			// user1 was on call the last day of the previous month, user2 twice
+			// the week before
+			s.SetHistory(map[string][]time.Time{
+				email(1): {day(-1)},
+				email(2): {day(-4), day(-2)},
+			})
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := violations(s, s.schedule, s.assignment); len(v) > 0 {
+				t.Errorf("got hard constraints violations %q", v)
+			}
+
+			if slices.ContainsFunc(s.assignment[0], func(u pagerduty.AssignedUser) bool { return u.Email == email(1) }) {
+				t.Errorf("got %s on call the first day, want a rest day after its last shift", email(1))
+			}
+			for k := 0; k <= 2; k++ {
+				if slices.ContainsFunc(s.assignment[k], func(u pagerduty.AssignedUser) bool { return u.Email == email(2) }) {
+					t.Errorf("got %s on call day %d, want at most 2 shifts in 7 days", email(2), k)
+				}
+			}
+		})
+	}
+}
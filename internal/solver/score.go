@@ -0,0 +1,159 @@
+package solver
+
+import (
+	"slices"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// Score evaluates a schedule with a weighted objective, the lower the better.
+type Score struct {
+	// ShiftsVariance is the variance of the number of shift days of users,
+	// ledger debt included and relative to their capacity.
+	ShiftsVariance float64 `json:"shifts_variance"`
+	// WeekendsVariance is the variance of the number of week-end shifts of
+	// users, ledger debt included and relative to their capacity.
+	WeekendsVariance float64 `json:"weekends_variance"`
+	// BackToBack counts users on-call two shifts in a row.
+	BackToBack int `json:"back_to_back"`
+	// Fallbacks counts users selected without checking fairness.
+	Fallbacks int `json:"fallbacks"`
+	// PreferenceViolations counts shifts assigned against users preferences,
+	// and preferred shifts not given.
+	PreferenceViolations int            `json:"preference_violations"`
+	Weights              config.Weights `json:"weights"`
+	Total                float64        `json:"total"`
+}
+
+// Score evaluates the schedule built by the last run.
+func (s *Solver) Score() Score {
+	return s.score(s.schedule, s.assignment, s.fallbacks)
+}
+
+func (s *Solver) score(shifts []shift, a assignment, fallbacks int) Score {
+	stats, weekends, _ := s.count(shifts, a)
+
+	sc := Score{
+		ShiftsVariance:       utils.Variance(s.load(stats, shiftsDebt)),
+		WeekendsVariance:     utils.Variance(s.load(weekends, weekendsDebt)),
+		BackToBack:           s.backToBack(shifts, a),
+		Fallbacks:            fallbacks,
+		PreferenceViolations: s.preferenceViolations(shifts, a),
+		Weights:              s.weights,
+	}
+
+	sc.Total = s.weights.ShiftsVariance*sc.ShiftsVariance +
+		s.weights.WeekendsVariance*sc.WeekendsVariance +
+		s.weights.BackToBack*float64(sc.BackToBack) +
+		s.weights.Fallbacks*float64(sc.Fallbacks) +
+		s.weights.PreferenceViolations*float64(sc.PreferenceViolations)
+
+	return sc
+}
+
+// count returns the number of shift days, week-end shifts and public holiday
+// shifts of users, shifts continued from the previous schedule only counting
+// for shift days.
+func (s *Solver) count(shifts []shift, a assignment) (stats, weekends, holidays map[string]int) {
+	stats = make(map[string]int, len(s.input.Users))
+	weekends = make(map[string]int, len(s.input.Users))
+	holidays = make(map[string]int, len(s.input.Users))
+
+	for _, user := range s.input.Users {
+		stats[user.Email] = 0
+		weekends[user.Email] = 0
+		holidays[user.Email] = 0
+	}
+
+	for k, sh := range shifts {
+		for _, u := range a[k] {
+			user, ok := s.inputUser(u)
+			if !ok {
+				continue
+			}
+
+			stats[user.Email] += len(sh.Days)
+			if sh.Continued {
+				continue
+			}
+
+			if sh.isWeekendFor(user, s.weekend) {
+				weekends[user.Email]++
+			}
+			if sh.isHolidayFor(user, s.holidays) {
+				holidays[user.Email]++
+			}
+		}
+	}
+
+	return stats, weekends, holidays
+}
+
+// backToBack counts users on-call the shift right after one of their shifts,
+// on any layer.
+func (s *Solver) backToBack(shifts []shift, a assignment) int {
+	n := 0
+
+	for k := 1; k < len(shifts); k++ {
+		if shifts[k].Continued {
+			continue
+		}
+
+		for _, u := range a[k] {
+			if slices.Contains(a[k-1], u) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// preferenceViolations counts shifts assigned against users preferences:
+// shifts of users available if needed only, days a prefer-together pair of
+// users is not on call together, and preferred shifts not given.
+func (s *Solver) preferenceViolations(shifts []shift, a assignment) int {
+	n := s.missedPreferences(shifts, a)
+	for _, count := range s.ifNeeded(shifts, a) {
+		n += count
+	}
+
+	for _, v := range s.pairingViolations(shifts, a) {
+		n += len(v.Days)
+	}
+
+	return n
+}
+
+// ifNeeded counts the shifts of users available if needed only, shifts
+// continued from the previous schedule excepted.
+func (s *Solver) ifNeeded(shifts []shift, a assignment) map[string]int {
+	counts := make(map[string]int, len(s.input.Users))
+
+	for k, sh := range shifts {
+		if sh.Continued {
+			continue
+		}
+
+		for _, u := range a[k] {
+			if user, ok := s.inputUser(u); ok && sh.isIfNeededFor(user) {
+				counts[user.Email]++
+			}
+		}
+	}
+
+	return counts
+}
+
+// inputUser returns the input user assigned to shifts.
+func (s *Solver) inputUser(u pagerduty.AssignedUser) (pagerduty.User, bool) {
+	for _, user := range s.input.Users {
+		if user.Email == u.Email {
+			return user, true
+		}
+	}
+
+	return pagerduty.User{}, false
+}
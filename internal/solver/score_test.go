@@ -0,0 +1,76 @@
+package solver
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestIfNeededFallback(t *testing.T) {
+	tests := []struct {
+		name  string
+		users func(users []pagerduty.User)
+		want  int
+	}{
+		{
+			name:  "enough available users",
+			users: func(users []pagerduty.User) { users[0].IfNeeded = firstDays(6) },
+		},
+		{
+			name: "not enough available users",
+			users: func(users []pagerduty.User) {
+				// user1, if needed, and user3 are the only users available the first day
+				users[0].IfNeeded = []time.Time{day(0)}
+				for _, i := range []int{1, 3, 4} {
+					users[i].Unavailable = []time.Time{day(0)}
+				}
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		// the local search trades if needed shifts for fairness, within the score
+		for _, te := range testEngines[:2] {
+			t.Run(tt.name+" "+te.name, func(t *testing.T) {
+				users := team(5)
+				tt.users(users)
+
+				s := newTestSolver(t, testConfig(), users, 6, nil, nil)
+				te.set(t, s)
+
+				if _, err := s.Run(); err != nil {
+					t.Fatal(err)
+				}
+
+				if got := s.IfNeededStats[email(1)]; got != tt.want {
+					t.Errorf("got %d shifts for %s if needed, want %d", got, email(1), tt.want)
+				}
+				if got := s.Score().PreferenceViolations; got != tt.want {
+					t.Errorf("got %d preference violations, want %d", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestIfNeededScore(t *testing.T) {
+	users := team(4)
+	users[0].IfNeeded = []time.Time{day(0)}
+	s := newTestSolver(t, testConfig(), users, 2, nil, nil)
+
+	shifts := s.shifts()
+	u := func(i int) pagerduty.AssignedUser { return pagerduty.AssignedUser{Email: email(i)} }
+
+	// the same shift counts, user1 taking the first day or the second one
+	ifNeeded := s.score(shifts, assignment{{u(1), u(2)}, {u(3), u(4)}}, 0)
+	available := s.score(shifts, assignment{{u(3), u(2)}, {u(1), u(4)}}, 0)
+
+	if ifNeeded.PreferenceViolations != 1 || available.PreferenceViolations != 0 {
+		t.Errorf("got %d and %d preference violations, want 1 and 0", ifNeeded.PreferenceViolations, available.PreferenceViolations)
+	}
+	if ifNeeded.Total-available.Total != s.weights.PreferenceViolations {
+		t.Errorf("got scores %.2f and %.2f, want them %.2f apart", ifNeeded.Total, available.Total, s.weights.PreferenceViolations)
+	}
+}
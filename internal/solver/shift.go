@@ -0,0 +1,256 @@
+package solver
+
+import (
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+	"github.com/jtbonhomme/goshift/internal/utils"
+)
+
+// shift is a period of one or several days assigned to a single user per layer.
+type shift struct {
+	Start   time.Time
+	End     time.Time
+	Days    []time.Time
+	Weekend bool
+	// PerDay shifts are published as one override per day.
+	PerDay bool
+	// Continued shifts started before the schedule start.
+	Continued bool
+	// Window is the index of the follow-the-sun window of the shift, if any.
+	Window int
+	window *config.Window
+}
+
+// shifts splits the schedule into shifts, depending on the shift length.
+func (s *Solver) shifts() []shift {
+	shifts := []shift{}
+	days := []time.Time{}
+
+	for d := s.input.ScheduleStart; d.Before(s.input.ScheduleEnd.Add(utils.OneDay)); d = d.Add(utils.OneDay) {
+		days = append(days, d)
+	}
+
+	perDay := s.shift.Length != config.Weekly && s.shift.Length != config.Custom
+
+	for i := 0; i < len(days); {
+		n := s.blockLength(days[i:])
+		sh := shift{
+			Start:  days[i],
+			End:    days[i+n-1].Add(utils.OneDay),
+			Days:   days[i : i+n],
+			PerDay: perDay,
+		}
+
+		for _, d := range sh.Days {
+			sh.Weekend = sh.Weekend || s.weekend.IsWeekend(d)
+		}
+
+		sh.Continued = i == 0 && s.startsBefore(days[0])
+
+		// week-end may start later than the usual handover time, the shift
+		// before it going on until then
+		first := sh.Days[0]
+		isFirstWeekendDay := !s.weekend.IsWeekend(first.Add(-utils.OneDay))
+		if perDay && sh.Weekend && s.weekend.Start != 0 && isFirstWeekendDay && len(shifts) > 0 {
+			sh.Start = utils.AtTimeOfDay(first, s.weekend.Start, first.Location())
+			shifts[len(shifts)-1].End = sh.Start
+		}
+
+		shifts = append(shifts, sh)
+		i += n
+	}
+
+	return s.splitWindows(shifts)
+}
+
+// splitWindows splits every shift into one shift per follow-the-sun window.
+func (s *Solver) splitWindows(shifts []shift) []shift {
+	if len(s.windows) == 0 {
+		return shifts
+	}
+
+	split := []shift{}
+	for _, sh := range shifts {
+		for k := range s.windows {
+			w := sh
+			w.Window = k
+			w.window = &s.windows[k]
+			// previous schedule last users are only known for one window
+			w.Continued = false
+			split = append(split, w)
+		}
+	}
+
+	return split
+}
+
+// blockLength returns the number of days of the shift starting the first given day.
+func (s *Solver) blockLength(days []time.Time) int {
+	n := 1
+
+	switch s.shift.Length {
+	case config.Daily:
+	case config.Weekly:
+		// weekly shifts end the day before the handover day
+		for n < len(days) && days[n].Weekday() != s.shift.HandoverWeekday() {
+			n++
+		}
+	case config.Custom:
+		n = min(s.shift.Days, len(days))
+	default:
+		// bundle consecutive week-end days
+		if s.weekend.IsWeekend(days[0]) && !s.weekend.Split {
+			for n < len(days) && s.weekend.IsWeekend(days[n]) {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+// startsBefore tells whether the shift covering the given day started the day before.
+func (s *Solver) startsBefore(d time.Time) bool {
+	switch s.shift.Length {
+	case config.Daily, config.Custom:
+		return false
+	case config.Weekly:
+		return d.Weekday() != s.shift.HandoverWeekday()
+	default:
+		return s.weekend.IsWeekend(d) && !s.weekend.Split && s.weekend.IsWeekend(d.Add(-utils.OneDay))
+	}
+}
+
+// coveredDays returns the days whose handover time is within the shift, the
+// days users answer availabilities for: a shift going on until a later
+// week-end start covers the next day, and a week-end shift starting after the
+// handover time does not cover its first day, unless it is its only day.
+func (sh shift) coveredDays() []time.Time {
+	if sh.window != nil {
+		return sh.Days
+	}
+
+	days := []time.Time{}
+	for _, d := range append(slices.Clip(sh.Days), sh.Days[len(sh.Days)-1].Add(utils.OneDay)) {
+		if !d.Before(sh.Start) && d.Before(sh.End) {
+			days = append(days, d)
+		}
+	}
+
+	if len(days) == 0 {
+		return sh.Days[:1]
+	}
+
+	return days
+}
+
+// isAvailable tells whether the user is available every day covered by the shift.
+func (sh shift) isAvailable(user pagerduty.User) bool {
+	return !slices.ContainsFunc(sh.coveredDays(), func(day time.Time) bool { return slices.Contains(user.Unavailable, day) })
+}
+
+// isIfNeededFor tells whether the user is only available if needed one day covered by the shift.
+func (sh shift) isIfNeededFor(user pagerduty.User) bool {
+	return slices.ContainsFunc(sh.coveredDays(), func(day time.Time) bool { return user.Availability(day) == pagerduty.IfNeeded })
+}
+
+// isWeekendFor tells whether the shift is a week-end shift for the user,
+// according to the user local week-end.
+func (sh shift) isWeekendFor(user pagerduty.User, weekend pagerduty.Weekend) bool {
+	for _, d := range sh.Days {
+		if user.IsWeekend(d, weekend) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasWorkdaysFor tells whether the shift covers days that are neither week-end
+// days nor public holidays for the user.
+func (sh shift) hasWorkdaysFor(user pagerduty.User, weekend pagerduty.Weekend, holidays pagerduty.Holidays) bool {
+	for _, d := range sh.Days {
+		if !user.IsWeekend(d, weekend) && !holidays.IsHoliday(user.Country, d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isHolidayFor tells whether the shift covers a public holiday of the user country.
+func (sh shift) isHolidayFor(user pagerduty.User, holidays pagerduty.Holidays) bool {
+	for _, d := range sh.Days {
+		if holidays.IsHoliday(user.Country, d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// overrides builds the PagerDuty overrides of a shift, one per day or one for
+// the whole shift.
+func (sh shift) overrides(user pagerduty.AssignedUser) []pagerduty.Override {
+	if sh.window != nil {
+		return sh.windowOverrides(user)
+	}
+
+	if !sh.PerDay {
+		return []pagerduty.Override{
+			{
+				Start: sh.Start,
+				End:   sh.End,
+				User:  user,
+			},
+		}
+	}
+
+	overrides := []pagerduty.Override{}
+
+	for i, d := range sh.Days {
+		o := pagerduty.Override{
+			Start: d,
+			End:   d.Add(utils.OneDay),
+			User:  user,
+		}
+
+		if i == 0 {
+			o.Start = sh.Start
+		}
+
+		if i == len(sh.Days)-1 {
+			o.End = sh.End
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	return overrides
+}
+
+// windowOverrides builds the PagerDuty overrides of a follow-the-sun shift, one
+// per day of the shift, covering the window time.
+func (sh shift) windowOverrides(user pagerduty.AssignedUser) []pagerduty.Override {
+	overrides := []pagerduty.Override{}
+	start, end, _ := sh.window.Times()
+
+	for _, d := range sh.Days {
+		o := pagerduty.Override{
+			Start: utils.AtTimeOfDay(d, start, d.Location()),
+			End:   utils.AtTimeOfDay(d, end, d.Location()),
+			User:  user,
+		}
+
+		if end <= start {
+			o.End = utils.AtTimeOfDay(d.AddDate(0, 0, 1), end, d.Location())
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	return overrides
+}
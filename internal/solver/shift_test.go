@@ -0,0 +1,166 @@
+package solver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestShiftsWeekendStart(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+	cfg.Weekend = pagerduty.Weekend{
+		Days:  []time.Weekday{time.Friday, time.Saturday, time.Sunday},
+		Start: 18 * time.Hour,
+	}
+
+	friday, saturday := day(4), day(5)
+	users := team(3)
+	users[0].Unavailable = []time.Time{friday}
+	users[1].Unavailable = []time.Time{saturday}
+
+	s := newTestSolver(t, cfg, users, 7, nil, nil)
+	shifts := s.shifts()
+	if len(shifts) != 5 {
+		t.Fatalf("got %d shifts, want 4 workdays and a week-end", len(shifts))
+	}
+
+	thursday, weekend := shifts[3], shifts[4]
+	evening := friday.Add(9 * time.Hour)
+	if !thursday.End.Equal(evening) || !weekend.Start.Equal(evening) {
+		t.Errorf("got thursday ending %s and week-end starting %s, want both at %s", thursday.End, weekend.Start, evening)
+	}
+
+	tests := []struct {
+		name      string
+		sh        shift
+		user      pagerduty.User
+		available bool
+	}{
+		{name: "thursday going on friday, unavailable friday", sh: thursday, user: users[0]},
+		{name: "thursday going on friday, unavailable saturday", sh: thursday, user: users[1], available: true},
+		{name: "week-end from friday evening, unavailable friday", sh: weekend, user: users[0], available: true},
+		{name: "week-end from friday evening, unavailable saturday", sh: weekend, user: users[1]},
+		{name: "week-end from friday evening, always available", sh: weekend, user: users[2], available: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sh.isAvailable(tt.user); got != tt.available {
+				t.Errorf("got available %t, want %t", got, tt.available)
+			}
+		})
+	}
+
+	// overrides cover the schedule without gap nor overlap
+	end := monday
+	for k, sh := range shifts {
+		for _, o := range sh.overrides(pagerduty.AssignedUser{Email: email(1)}) {
+			if !o.Start.Equal(end) {
+				t.Errorf("shift %d: got override from %s, want from %s", k, o.Start, end)
+			}
+			end = o.End
+		}
+	}
+}
+
+func TestShiftsWeekendStartOnFirstDay(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+	cfg.Weekend = pagerduty.Weekend{
+		Days:  []time.Weekday{time.Friday, time.Saturday, time.Sunday},
+		Start: 18 * time.Hour,
+	}
+
+	s := newTestSolver(t, cfg, team(3), 7, nil, nil)
+	// a schedule starting on the first week-end day has no shift to go on until the week-end start
+	s.input.ScheduleStart = day(4)
+
+	shifts := s.shifts()
+	if !shifts[0].Start.Equal(day(4)) {
+		t.Errorf("got the first week-end starting %s, want %s", shifts[0].Start, day(4))
+	}
+}
+
+func TestShiftsBlocks(t *testing.T) {
+	tests := []struct {
+		name      string
+		shift     config.Shift
+		from      int
+		days      int
+		want      []int
+		continued bool
+	}{
+		{name: "weekly", shift: config.Shift{Length: config.Weekly}, days: 14, want: []int{7, 7}},
+		{name: "weekly ending partway through a week", shift: config.Shift{Length: config.Weekly}, days: 10, want: []int{7, 3}},
+		{name: "weekly starting partway through a week", shift: config.Shift{Length: config.Weekly}, from: 2, days: 12, want: []int{5, 7}, continued: true},
+		{name: "weekly from wednesday", shift: config.Shift{Length: config.Weekly, HandoverDay: "Wednesday"}, days: 10, want: []int{2, 7, 1}, continued: true},
+		{name: "custom", shift: config.Shift{Length: config.Custom, Days: 3}, days: 9, want: []int{3, 3, 3}},
+		{name: "custom ending partway through a block", shift: config.Shift{Length: config.Custom, Days: 3}, days: 8, want: []int{3, 3, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Shift = tt.shift
+
+			s := newTestSolver(t, cfg, team(3), tt.from+tt.days, nil, nil)
+			s.input.ScheduleStart = day(tt.from)
+
+			shifts := s.shifts()
+			got := []int{}
+			for _, sh := range shifts {
+				got = append(got, len(sh.Days))
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got blocks of %v days, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got blocks of %v days, want %v", got, tt.want)
+				}
+			}
+
+			if shifts[0].Continued != tt.continued {
+				t.Errorf("got first block continued %t, want %t", shifts[0].Continued, tt.continued)
+			}
+			if last := shifts[len(shifts)-1]; !last.End.Equal(day(tt.from + tt.days)) {
+				t.Errorf("got last block ending %s, want %s", last.End, day(tt.from+tt.days))
+			}
+		})
+	}
+}
+
+func TestWeeklyBlockUnavailability(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift = config.Shift{Length: config.Weekly}
+
+	// every user misses one day of the first week
+	users := team(4)
+	for i := range users {
+		users[i].Unavailable = []time.Time{day(i)}
+	}
+
+	s := newTestSolver(t, cfg, users, 7, nil, nil)
+	_, err := s.Run()
+
+	var infeasible *InfeasibleError
+	if !errors.As(err, &infeasible) {
+		t.Fatalf("got error %v, want an infeasible error", err)
+	}
+
+	if !strings.Contains(err.Error(), "no user is available every day of the shift") {
+		t.Errorf("got error %q, want the block length to be reported", err)
+	}
+
+	for _, r := range infeasible.Rejections {
+		if r.Reason != PartlyAvailable {
+			t.Errorf("got %s rejected as %q, want %q", r.Email, r.Reason, PartlyAvailable)
+		}
+	}
+}
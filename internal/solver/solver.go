@@ -2,133 +2,408 @@ package solver
 
 import (
 	"fmt"
+	"math/rand"
+	"slices"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/jtbonhomme/goshift/internal/config"
 	"github.com/jtbonhomme/goshift/internal/pagerduty"
-	"github.com/jtbonhomme/goshift/internal/utils"
 )
 
+// Engines assigning users to shifts.
+const (
+	// Greedy assigns users shift after shift, without going back.
+	Greedy string = "greedy"
+	// Exact searches all assignments for the fairest one.
+	Exact string = "exact"
+)
+
+// assignment holds the users assigned to each shift, per layer.
+type assignment [][]pagerduty.AssignedUser
+
 type Solver struct {
-	input             pagerduty.Input
-	users             pagerduty.Users
-	Stats             map[string]int
-	WeekendStats      map[string]int
+	engine string
+	input  pagerduty.Input
+	users  pagerduty.Users
+	layers []config.Layer
+	// shadows are the shadow layers, filled once on-call layers are.
+	shadows      []config.Layer
+	weekend      pagerduty.Weekend
+	shift        config.Shift
+	windows      []config.Window
+	holidays     pagerduty.Holidays
+	Stats        map[string]int
+	WeekendStats map[string]int
+	HolidayStats map[string]int
+	ShadowStats  map[string]int
+	// IfNeededStats counts the shifts of users available if needed only.
+	IfNeededStats map[string]int
+	// Debt is the balance of users against the team average in previous months.
+	Debt              map[string]Balance
 	newbies           []string
+	excludedUsers     [][]string
+	windowExcluded    [][]string
 	lastAssignedUsers []pagerduty.AssignedUser
+	weights           config.Weights
+	rules             config.Rules
+	// apart and together are the never-together and prefer-together pairs of users.
+	apart    [][2]string
+	together [][2]string
+	// pins are the agreed-upon assignments, pinning holds their users per
+	// shift and layer for the last run, and pinnedDuties the days of their shifts.
+	pins         []config.Pin
+	pinning      assignment
+	pinnedDuties map[string][][]time.Time
+	// history holds the days of the shifts taken by users in the previous
+	// schedule, and duties the ones of the last run, history included.
+	history map[string][][]time.Time
+	duties  map[string][][]time.Time
+	// schedule and assignment built by the last run.
+	schedule   []shift
+	assignment assignment
+	shadowing  assignment
+	fallbacks  int
+	// improveIterations is the number of iterations of the local search,
+	// disabled when zero, and improveLimit its time limit.
+	improveIterations int
+	improveLimit      time.Duration
+	rand              *rand.Rand
 }
 
-func New(input pagerduty.Input, users pagerduty.Users, newbies, lastUsers []string) *Solver {
+func New(cfg config.Config, input pagerduty.Input, users pagerduty.Users, newbies, lastUsers []string) *Solver {
 	// initialize maps
 	Stats := make(map[string]int, len(input.Users))
 	WeekendStats := make(map[string]int, len(input.Users))
+	HolidayStats := make(map[string]int, len(input.Users))
 
 	for _, user := range input.Users {
 		Stats[user.Email] = 0
 		WeekendStats[user.Email] = 0
+		HolidayStats[user.Email] = 0
 	}
 
-	lastAssignedUsers := []pagerduty.AssignedUser{}
-	for _, email := range lastUsers {
+	// last users stay in layers order, unknown ones being left empty
+	lastAssignedUsers := make([]pagerduty.AssignedUser, len(lastUsers))
+	for i, email := range lastUsers {
+		if email == "" {
+			continue
+		}
+
 		u, err := users.RetrieveAssignedUserByEmail(email)
 		if err != nil {
 			log.Debug().Msgf("error: %s", err.Error())
 			continue
 		}
-		lastAssignedUsers = append(lastAssignedUsers, u)
+		lastAssignedUsers[i] = u
 	}
 
-	return &Solver{
+	s := &Solver{
+		engine:            Greedy,
 		input:             input,
 		users:             users,
+		weekend:           cfg.Weekend,
+		shift:             cfg.Shift,
+		windows:           cfg.Windows,
+		weights:           cfg.Weights,
+		rules:             cfg.Rules,
+		history:           make(map[string][][]time.Time),
+		rand:              rand.New(rand.NewSource(1)),
 		Stats:             Stats,
+		ShadowStats:       make(map[string]int, len(input.Users)),
 		WeekendStats:      WeekendStats,
+		HolidayStats:      HolidayStats,
+		holidays:          pagerduty.Holidays{},
 		newbies:           newbies,
 		lastAssignedUsers: lastAssignedUsers,
 	}
+
+	for _, layer := range cfg.Layers {
+		if layer.Shadow {
+			s.shadows = append(s.shadows, layer)
+			continue
+		}
+		s.layers = append(s.layers, layer)
+	}
+
+	s.excludedUsers = make([][]string, len(s.layers))
+	for i, layer := range s.layers {
+		s.excludedUsers[i] = s.excluded(layer)
+	}
+
+	s.windowExcluded = make([][]string, len(s.windows))
+	for k, window := range s.windows {
+		s.windowExcluded[k] = []string{}
+		for _, user := range s.input.Users {
+			if len(window.Regions) > 0 && !slices.Contains(window.Regions, user.Region) {
+				s.windowExcluded[k] = append(s.windowExcluded[k], user.Email)
+			}
+		}
+	}
+
+	return s
+}
+
+// onEveryLayer tells whether users are known for every layer, in layers order.
+func (s *Solver) onEveryLayer(users []pagerduty.AssignedUser) bool {
+	return len(users) == len(s.layers) && !slices.Contains(users, pagerduty.AssignedUser{})
+}
+
+// excludedFor lists the users that are not eligible for a layer during a shift.
+func (s *Solver) excludedFor(layer int, sh shift) []string {
+	if sh.window == nil {
+		return s.excludedUsers[layer]
+	}
+
+	excluded := append([]string{}, s.excludedUsers[layer]...)
+	return append(excluded, s.windowExcluded[sh.Window]...)
+}
+
+// excluded lists the users that are not eligible for a layer.
+func (s *Solver) excluded(layer config.Layer) []string {
+	excluded := []string{}
+
+	for _, user := range s.input.Users {
+		if s.layerIneligibility(layer, user) != "" {
+			excluded = append(excluded, user.Email)
+		}
+	}
+
+	return excluded
+}
+
+// layerIneligibility returns why a user is not eligible for a layer, if so.
+func (s *Solver) layerIneligibility(layer config.Layer, user pagerduty.User) string {
+	switch {
+	case layer.Shadow && !slices.Contains(s.newbies, user.Email):
+		return NotNewbie
+	// newbies are not allowed to do secondary
+	case layer.ExcludeNewbies && slices.Contains(s.newbies, user.Email):
+		return Newbie
+	case len(layer.Emails) > 0 && !slices.Contains(layer.Emails, user.Email):
+		return NotInLayer
+	case len(layer.Roles) > 0 && !slices.Contains(layer.Roles, s.teamRole(user.Email)):
+		return RoleNotAllowed
+	default:
+		return ""
+	}
+}
+
+func (s *Solver) teamRole(email string) string {
+	for _, u := range s.users.Users {
+		if u.Email == email {
+			return u.TeamRole
+		}
+	}
+
+	return ""
+}
+
+// SetHolidays gives the public holidays, balanced like week-ends between users.
+func (s *Solver) SetHolidays(holidays pagerduty.Holidays) {
+	s.holidays = holidays
+}
+
+// SetSeed seeds the random choices of the solver: ties between users and the
+// local search, so that a schedule can be built again.
+func (s *Solver) SetSeed(seed int64) {
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
+// SetEngine selects the engine used to assign users to shifts.
+func (s *Solver) SetEngine(engine string) error {
+	switch engine {
+	case Greedy, Exact:
+		s.engine = engine
+	default:
+		return fmt.Errorf("unknown engine %s", engine)
+	}
+
+	return nil
 }
 
-func (s *Solver) Run() (pagerduty.Overrides, pagerduty.Overrides, error) {
+// Run builds one override schedule per layer.
+func (s *Solver) Run() ([]pagerduty.Overrides, error) {
+	shifts := s.shifts()
+	s.fallbacks = 0
+
+	s.duties = make(map[string][][]time.Time, len(s.history))
+	for email, duties := range s.history {
+		s.duties[email] = slices.Clone(duties)
+	}
+	s.pin(shifts)
+
+	var a assignment
 	var err error
-	var overridesPrimary = pagerduty.Overrides{
-		Overrides: []pagerduty.Override{},
+
+	switch s.engine {
+	case Exact:
+		a, err = s.exact(shifts)
+	default:
+		a, err = s.greedy(shifts)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if s.improveIterations > 0 {
+		a = s.improve(shifts, a)
+		s.setStats(shifts, a)
+	}
+
+	s.schedule = shifts
+	s.assignment = a
+	s.IfNeededStats = s.ifNeeded(shifts, a)
+	s.shadowing = s.shadow(shifts, a)
+
+	return append(s.build(shifts, a, s.layers), s.build(shifts, s.shadowing, s.shadows)...), nil
+}
+
+// build turns the users assigned to shifts into one override schedule per layer,
+// shifts without user being skipped.
+func (s *Solver) build(shifts []shift, a assignment, layers []config.Layer) []pagerduty.Overrides {
+	overrides := make([]pagerduty.Overrides, len(layers))
+	for i := range overrides {
+		overrides[i] = pagerduty.Overrides{
+			Overrides: []pagerduty.Override{},
+		}
 	}
-	var overridesSecondary = pagerduty.Overrides{
-		Overrides: []pagerduty.Override{},
+
+	for k, sh := range shifts {
+		for i, u := range a[k] {
+			if u.Email == "" {
+				continue
+			}
+			overrides[i].Overrides = append(overrides[i].Overrides, sh.overrides(u)...)
+		}
 	}
 
+	return overrides
+}
+
+// greedy assigns users shift after shift, picking the user with the least
+// remaining availabilities, and falls back to the user with the least shifts.
+func (s *Solver) greedy(shifts []shift) (assignment, error) { //nolint:funlen,gocyclo // todo
+	a := assignment{}
+
+	// last assigned users per follow-the-sun window
+	last := make(map[int][]pagerduty.AssignedUser)
+	// users assigned to the other windows of the current shift
+	var blockStart time.Time
+	blockUsers := []pagerduty.AssignedUser{}
+
 	// build shifts
-	for d := s.input.ScheduleStart; d.Before(s.input.ScheduleEnd.Add(utils.OneDay)); d = d.Add(utils.OneDay) {
-		weekday := d.Weekday().String()
+	for k, sh := range shifts {
+		previous, ok := last[sh.Window]
+		if !ok {
+			previous = s.lastAssignedUsers
+		}
+
+		if !sh.Days[0].Equal(blockStart) {
+			blockStart = sh.Days[0]
+			blockUsers = []pagerduty.AssignedUser{}
+		}
+
+		// shift started at the end of the previous schedule goes on with the same users
+		if sh.Continued && s.onEveryLayer(previous) {
+			for _, u := range previous {
+				s.Stats[u.Email] += len(sh.Days)
+				addDuty(s.duties, u.Email, sh.Days, true)
+			}
+			a = append(a, previous)
+			last[sh.Window] = previous
+			continue
+		}
+
+		// the previous shift in time order is the last window of the previous day
+		if len(s.windows) > 0 && sh.Window == 0 && k > 0 {
+			previous = append(slices.Clone(previous), a[k-1]...)
+		}
+
+		lastUsers := append(append([]pagerduty.AssignedUser{}, previous...), blockUsers...)
+		sameDay := slices.Clone(blockUsers)
+		pinnedNear := s.pinnedNear(k)
 
 		// rank and sort available users depending of their number of available days
-		sortedUsers := sortUsers(d, s.input.Users, s.Stats, "PerRemainingAvailability")
+		sortedUsers := sortUsers(sh, s.weekend, s.input.Users, s.Stats, "PerRemainingAvailability", s.rand)
 		ui := pagerduty.NewIterator(sortedUsers)
 
-		primary := s.processOverride("🅰️", d, s.lastAssignedUsers, ui, false, true)
-		s.lastAssignedUsers = append(s.lastAssignedUsers, primary.User)
-		secondary := s.processOverride("🅱️", d, s.lastAssignedUsers, ui, true, true)
+		// a user can not be on two layers the same day
+		selected := make([]pagerduty.AssignedUser, len(s.layers))
 
-		// check shift
-		if primary.User.Name == "" {
-			log.Debug().Msg("⚠️ \tcould not find any primary, need to reselect another user \t⚠️")
-			// rank and sort available users depending of their stats
-			sorted := sortUsers(d, s.input.Users, s.Stats, "PerStats")
-			sui := pagerduty.NewIterator(sorted)
-			// try to pick very first name available
-			primary = s.processOverride("🅰️", d, s.lastAssignedUsers, sui, false, false)
-			s.lastAssignedUsers = append(s.lastAssignedUsers, primary.User)
-			if primary.User.Name == "" {
-				return pagerduty.Overrides{}, pagerduty.Overrides{}, fmt.Errorf("empty user for primary on %s", primary.Start)
+		// pinned users are set first, and never changed
+		for i, u := range s.pinning[k] {
+			if u.Email == "" {
+				continue
 			}
+
+			user, _ := s.inputUser(u)
+			s.record(sh, user)
+			selected[i] = u
+			lastUsers = append(lastUsers, u)
+			sameDay = append(sameDay, u)
 		}
 
-		if secondary.User.Name == "" {
-			log.Debug().Msg("⚠️ \tcould not find any secondary, need to reselect another user \t⚠️")
+		for i, layer := range s.layers {
+			if s.isPinned(k, i) {
+				continue
+			}
+
+			excluded := append(append(slices.Clip(s.excludedFor(i, sh)), pinnedNear...), s.unpairedUsers(i, sh, selected, lastUsers)...)
+
+			// users preferably on call with the users of the day are tried first
+			if preferred := s.preferredPartners(sameDay); len(preferred) > 0 {
+				selected[i] = s.processOverride(layer.Name, sh, lastUsers, pagerduty.NewIterator(preferred), excluded, sameDay, true, false)
+			}
+			if selected[i].Name == "" {
+				selected[i] = s.processOverride(layer.Name, sh, lastUsers, ui, excluded, sameDay, true, false)
+			}
+			lastUsers = append(lastUsers, selected[i])
+			sameDay = append(sameDay, selected[i])
+		}
+
+		// check shifts
+		for i, layer := range s.layers {
+			if selected[i].Name != "" {
+				continue
+			}
+
+			log.Debug().Msgf("⚠️ \tcould not find any %s, need to reselect another user \t⚠️", layer.Name)
 			// rank and sort available users depending of their stats
-			sorted := sortUsers(d, s.input.Users, s.Stats, "PerStats")
+			sorted := sortUsers(sh, s.weekend, s.input.Users, percent(s.load(s.Stats, shiftsDebt)), "PerStats", s.rand)
 			sui := pagerduty.NewIterator(sorted)
 			// try to pick very first name available
-			secondary = s.processOverride("🅱️", d, s.lastAssignedUsers, sui, true, false)
-			s.lastAssignedUsers = append(s.lastAssignedUsers, secondary.User)
-			if secondary.User.Name == "" {
-				return pagerduty.Overrides{}, pagerduty.Overrides{}, fmt.Errorf("empty user for secondary on %s", secondary.Start)
+			unpaired := s.unpairedUsers(i, sh, selected, lastUsers)
+			excluded := append(append(slices.Clip(s.excludedFor(i, sh)), pinnedNear...), unpaired...)
+			selected[i] = s.processOverride(layer.Name, sh, lastUsers, sui, excluded, sameDay, false, false)
+			if selected[i].Name == "" {
+				// users available if needed are only selected as a last resort
+				selected[i] = s.processOverride(layer.Name, sh, lastUsers, sui, excluded, sameDay, false, true)
+			}
+			lastUsers = append(lastUsers, selected[i])
+			if selected[i].Name == "" {
+				return nil, s.diagnose(i, sh, previous, lastUsers, unpaired)
 			}
+			sameDay = append(sameDay, selected[i])
+			s.fallbacks++
 		}
 
-		if primary.User == secondary.User {
-			return pagerduty.Overrides{}, pagerduty.Overrides{}, fmt.Errorf("same user for primary and secondary on %s", primary.Start)
+		for i := range selected {
+			for j := i + 1; j < len(selected); j++ {
+				if selected[i] == selected[j] {
+					return nil, fmt.Errorf("same user for %s and %s on %s", s.layers[i].Name, s.layers[j].Name, sh.Start)
+				}
+			}
 		}
 
 		log.Debug().Msg("")
 
-		overridesPrimary.Overrides = append(overridesPrimary.Overrides, primary)
-		overridesSecondary.Overrides = append(overridesSecondary.Overrides, secondary)
-
-		// weekday management
-		if weekday == time.Saturday.String() && d.Before(s.input.ScheduleEnd) {
-			overridesPrimary.Overrides = append(overridesPrimary.Overrides, pagerduty.Override{
-				Start: primary.Start.Add(utils.OneDay),
-				End:   primary.End.Add(utils.OneDay),
-				User:  primary.User,
-			})
-			overridesSecondary.Overrides = append(overridesSecondary.Overrides, pagerduty.Override{
-				Start: secondary.Start.Add(utils.OneDay),
-				End:   secondary.End.Add(utils.OneDay),
-				User:  secondary.User,
-			})
-
-			s.Stats[primary.User.Email]++
-			s.Stats[secondary.User.Email]++
-			s.WeekendStats[primary.User.Email]++
-			s.WeekendStats[secondary.User.Email]++
-			d = d.Add(utils.OneDay)
-		}
-
-		s.lastAssignedUsers = []pagerduty.AssignedUser{}
-		s.lastAssignedUsers = append(s.lastAssignedUsers, primary.User, secondary.User)
+		a = append(a, selected)
+		last[sh.Window] = selected
+		blockUsers = append(blockUsers, selected...)
 	}
 
-	return overridesPrimary, overridesSecondary, err
+	return a, nil
 }
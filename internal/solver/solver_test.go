@@ -0,0 +1,240 @@
+package solver
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/config"
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// monday is the first day of test schedules, Monday September 2nd 2024.
+var monday = time.Date(2024, 9, 2, 9, 0, 0, 0, time.UTC)
+
+// day returns the i-th day of test schedules.
+func day(i int) time.Time {
+	return monday.AddDate(0, 0, i)
+}
+
+func email(i int) string {
+	return fmt.Sprintf("user%d@email.com", i)
+}
+
+// team returns n users available every day, user1@email.com to userN@email.com.
+func team(n int) []pagerduty.User {
+	users := []pagerduty.User{}
+	for i := 1; i <= n; i++ {
+		users = append(users, pagerduty.User{Name: fmt.Sprintf("User%d", i), Email: email(i), ID: fmt.Sprintf("P%d", i)})
+	}
+
+	return users
+}
+
+// testConfig returns the default primary and secondary layers config, with
+// daily shifts.
+func testConfig() config.Config {
+	cfg := config.Default("", "")
+	cfg.Shift.Length = config.Daily
+
+	return cfg
+}
+
+// newTestSolver returns a solver of users for a schedule of the given number
+// of days from monday, users being known in PagerDuty.
+func newTestSolver(t *testing.T, cfg config.Config, users []pagerduty.User, days int, newbies, lastUsers []string) *Solver {
+	t.Helper()
+
+	input := pagerduty.Input{
+		ScheduleStart: monday,
+		ScheduleEnd:   day(days - 1),
+		Users:         users,
+	}
+	known := pagerduty.Users{Users: users}
+
+	if err := cfg.ApplyUsers(input.Users, known); err != nil {
+		t.Fatal(err)
+	}
+
+	return New(cfg, input, known, newbies, lastUsers)
+}
+
+// testEngine builds schedules with an engine, followed by the local search
+// when iterations are given.
+type testEngine struct {
+	name       string
+	engine     string
+	iterations int
+}
+
+// testEngines lists the ways to build schedules.
+var testEngines = []testEngine{
+	{name: Greedy, engine: Greedy},
+	{name: Exact, engine: Exact},
+	{name: "improve", engine: Greedy, iterations: testImproveIterations},
+}
+
+// set sets the engine and the local search of a solver.
+func (te testEngine) set(t *testing.T, s *Solver) {
+	t.Helper()
+
+	if err := s.SetEngine(te.engine); err != nil {
+		t.Fatal(err)
+	}
+	s.SetImprove(te.iterations, 0)
+}
+
+// firstDays returns the first days of test schedules.
+func firstDays(n int) []time.Time {
+	d := []time.Time{}
+	for i := 0; i < n; i++ {
+		d = append(d, day(i))
+	}
+
+	return d
+}
+
+// violations lists the hard constraints an assignment breaks, checked on the
+// whole schedule rather than the way engines build it. Continued and pinned
+// slots are taken as given.
+func violations(s *Solver, shifts []shift, a assignment) []string {
+	v := []string{}
+	windows := max(1, len(s.windows))
+	continued := shifts[0].Continued && s.onEveryLayer(s.lastAssignedUsers)
+	given := func(k, l int) bool { return (k == 0 && continued) || s.isPinned(k, l) }
+	stats, weekends := map[string]int{}, map[string]int{}
+
+	for k, sh := range shifts {
+		for l, u := range a[k] {
+			at := fmt.Sprintf("%s on %s %s", u.Email, s.layers[l].Name, sh.Start.Format(time.DateOnly))
+			user, ok := s.inputUser(u)
+			if !ok {
+				v = append(v, at+": no user")
+				continue
+			}
+
+			stats[user.Email] += len(sh.Days)
+			if !sh.Continued && sh.isWeekendFor(user, s.weekend) {
+				weekends[user.Email]++
+			}
+
+			switch {
+			case k == 0 && continued:
+				if u != s.lastAssignedUsers[l] {
+					v = append(v, at+": not the previous user")
+				}
+				continue
+			case s.isPinned(k, l):
+				if u != s.pinning[k][l] {
+					v = append(v, at+": not the pinned user")
+				}
+				continue
+			}
+
+			if s.ineligibility(l, sh, user) != "" {
+				v = append(v, at+": ineligible")
+			}
+			if !sh.isAvailable(user) {
+				v = append(v, at+": unavailable")
+			}
+			if k < windows && !continued && slices.Contains(s.lastAssignedUsers, u) {
+				v = append(v, at+": worked the previous shift")
+			}
+
+			// the other layers and windows of the day, the previous shift of
+			// the window and the previous shift in time
+			for j := k - windows; j <= k; j++ {
+				if j < 0 || (j < k-k%windows && j != k-windows && j != k-1) {
+					continue
+				}
+				for l2, other := range a[j] {
+					if (j != k || l2 != l) && other == u && !given(j, l2) {
+						v = append(v, at+fmt.Sprintf(": also on shift %d layer %d", j, l2))
+					}
+				}
+			}
+
+			if s.apartFrom(u.Email, append(slices.Clone(a[k]), s.sameDay(shifts, a, k)...)) {
+				v = append(v, at+": never together with a user on call")
+			}
+
+			duties := slices.Clone(s.history[u.Email])
+			for j := range shifts {
+				if j != k && slices.Contains(a[j], u) {
+					duties = append(duties, shifts[j].Days)
+				}
+			}
+			if s.hasRules() && s.ruleViolation(sh, user, duties) != "" {
+				v = append(v, at+": "+s.ruleViolation(sh, user, duties))
+			}
+		}
+
+		emails := []string{}
+		for _, u := range a[k] {
+			emails = append(emails, u.Email)
+		}
+		if !(k == 0 && continued) && s.mentoring() && s.unpaired(emails) {
+			v = append(v, fmt.Sprintf("%v on %s: newbie without mentor", emails, sh.Start.Format(time.DateOnly)))
+		}
+	}
+
+	for _, user := range s.input.Users {
+		if user.MaxShifts > 0 && stats[user.Email] > user.MaxShifts {
+			v = append(v, fmt.Sprintf("%s: %d shift days over %d", user.Email, stats[user.Email], user.MaxShifts))
+		}
+		if user.MaxWeekends > 0 && weekends[user.Email] > user.MaxWeekends {
+			v = append(v, fmt.Sprintf("%s: %d week-ends over %d", user.Email, weekends[user.Email], user.MaxWeekends))
+		}
+	}
+
+	return v
+}
+
+func TestNewLastUsersLayers(t *testing.T) {
+	s := newTestSolver(t, testConfig(), team(3), 7, nil, []string{"", email(2)})
+
+	if len(s.lastAssignedUsers) != 2 || s.lastAssignedUsers[0] != (pagerduty.AssignedUser{}) || s.lastAssignedUsers[1].Email != email(2) {
+		t.Errorf("got last users %v, want the secondary user on the secondary layer only", s.lastAssignedUsers)
+	}
+}
+
+func TestContinuedShift(t *testing.T) {
+	cfg := testConfig()
+	cfg.Shift.Length = config.WeekendBundled
+
+	tests := []struct {
+		name      string
+		lastUsers []string
+		continued bool
+	}{
+		{name: "every layer known", lastUsers: []string{email(1), email(2)}, continued: true},
+		{name: "primary layer unknown", lastUsers: []string{"", email(2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestSolver(t, cfg, team(4), 4, nil, tt.lastUsers)
+			// a schedule starting on Sunday continues the Saturday shift
+			s.input.ScheduleStart = day(-1)
+
+			if _, err := s.Run(); err != nil {
+				t.Fatal(err)
+			}
+
+			first := s.assignment[0]
+			if tt.continued {
+				if first[0].Email != email(1) || first[1].Email != email(2) {
+					t.Errorf("got %v on the continued shift, want the previous users in layers order", first)
+				}
+				return
+			}
+
+			for l, u := range first {
+				if u.Email == "" || u.Email == email(2) {
+					t.Errorf("got %q on layer %d of the first shift, want an other user than the previous secondary one", u.Email, l)
+				}
+			}
+		})
+	}
+}
@@ -2,7 +2,7 @@ package solver
 
 import (
 	"math"
-	"time"
+	"math/rand"
 
 	"github.com/jtbonhomme/goshift/internal/pagerduty"
 )
@@ -13,34 +13,53 @@ const (
 	StatsPenaltyFactor      int = 2
 )
 
-func maxIndex(a []int) int {
-	var max, maxIndex int
+// maxIndex returns the index of the highest value, ties being broken at random.
+func maxIndex(a []int, r *rand.Rand) int {
+	var maxIndex, ties int
+	max := math.MinInt
 
 	for i := 0; i < len(a); i++ {
-		if a[i] > max {
+		switch {
+		case a[i] > max:
 			max = a[i]
 			maxIndex = i
+			ties = 1
+		case a[i] == max:
+			// each tied value is picked with the same probability
+			ties++
+			if r.Intn(ties) == 0 {
+				maxIndex = i
+			}
 		}
 	}
 
 	return maxIndex
 }
 
-func minIndex(a []int) int {
-	var min, minIndex int
-	min = math.MaxInt
+// minIndex returns the index of the lowest value, ties being broken at random.
+func minIndex(a []int, r *rand.Rand) int {
+	var minIndex, ties int
+	min := math.MaxInt
 
 	for i := 0; i < len(a); i++ {
-		if a[i] < min {
+		switch {
+		case a[i] < min:
 			min = a[i]
 			minIndex = i
+			ties = 1
+		case a[i] == min:
+			// each tied value is picked with the same probability
+			ties++
+			if r.Intn(ties) == 0 {
+				minIndex = i
+			}
 		}
 	}
 
 	return minIndex
 }
 
-func sortUsersPerAvailability(users []pagerduty.User) []pagerduty.User {
+func sortUsersPerAvailability(users []pagerduty.User, r *rand.Rand) []pagerduty.User {
 	var sortedUsers = []pagerduty.User{}
 
 	// rank users
@@ -51,7 +70,7 @@ func sortUsersPerAvailability(users []pagerduty.User) []pagerduty.User {
 
 	// sort per ranking
 	for i := 0; i < len(users); i++ {
-		j := maxIndex(rank)
+		j := maxIndex(rank, r)
 		sortedUsers = append(sortedUsers, users[j])
 		rank[j] = -1
 	}
@@ -59,7 +78,7 @@ func sortUsersPerAvailability(users []pagerduty.User) []pagerduty.User {
 	return sortedUsers
 }
 
-func sortUsersPerAvailabilitySimple(users []pagerduty.User) []pagerduty.User {
+func sortUsersPerAvailabilitySimple(users []pagerduty.User, r *rand.Rand) []pagerduty.User {
 	var sortedUsers = []pagerduty.User{}
 
 	// rank users
@@ -70,7 +89,7 @@ func sortUsersPerAvailabilitySimple(users []pagerduty.User) []pagerduty.User {
 
 	// sort per ranking
 	for i := 0; i < len(users); i++ {
-		j := maxIndex(rank)
+		j := maxIndex(rank, r)
 		sortedUsers = append(sortedUsers, users[j])
 		rank[j] = -1
 	}
@@ -78,16 +97,15 @@ func sortUsersPerAvailabilitySimple(users []pagerduty.User) []pagerduty.User {
 	return sortedUsers
 }
 
-func sortUsersPerRemainingAvailability(d time.Time, users []pagerduty.User) []pagerduty.User {
+func sortUsersPerRemainingAvailability(sh shift, weekend pagerduty.Weekend, users []pagerduty.User, r *rand.Rand) []pagerduty.User {
 	var sortedUsers = []pagerduty.User{}
+	d := sh.Days[len(sh.Days)-1]
 
-	// rank users
+	// rank users, only remaining week-ends matter for week-end shifts
 	var rank = make([]int, len(users))
 	for i, user := range users {
 		for _, a := range user.Unavailable {
-			if a.After(d) &&
-				(d.Weekday().String() != time.Saturday.String() || (d.Weekday().String() == time.Saturday.String() &&
-					a.Weekday().String() == time.Saturday.String())) {
+			if a.After(d) && (!sh.isWeekendFor(user, weekend) || user.IsWeekend(a, weekend)) {
 				rank[i]++
 			}
 		}
@@ -95,7 +113,7 @@ func sortUsersPerRemainingAvailability(d time.Time, users []pagerduty.User) []pa
 
 	// sort per ranking
 	for i := 0; i < len(users); i++ {
-		j := maxIndex(rank)
+		j := maxIndex(rank, r)
 		sortedUsers = append(sortedUsers, users[j])
 		rank[j] = -1
 	}
@@ -103,7 +121,7 @@ func sortUsersPerRemainingAvailability(d time.Time, users []pagerduty.User) []pa
 	return sortedUsers
 }
 
-func sortUsersPerStats(users []pagerduty.User, stats map[string]int) []pagerduty.User {
+func sortUsersPerStats(users []pagerduty.User, stats map[string]int, r *rand.Rand) []pagerduty.User {
 	var sortedUsers = []pagerduty.User{}
 
 	// rank users
@@ -114,7 +132,7 @@ func sortUsersPerStats(users []pagerduty.User, stats map[string]int) []pagerduty
 
 	// sort per ranking
 	for i := 0; i < len(users); i++ {
-		j := minIndex(rank)
+		j := minIndex(rank, r)
 		sortedUsers = append(sortedUsers, users[j])
 		rank[j] = math.MaxInt
 	}
@@ -122,7 +140,7 @@ func sortUsersPerStats(users []pagerduty.User, stats map[string]int) []pagerduty
 	return sortedUsers
 }
 
-func sortUsersPerAvailabilityAndStats(users []pagerduty.User, stats map[string]int) []pagerduty.User {
+func sortUsersPerAvailabilityAndStats(users []pagerduty.User, stats map[string]int, r *rand.Rand) []pagerduty.User {
 	var sortedUsers = []pagerduty.User{}
 
 	// rank users
@@ -137,7 +155,7 @@ func sortUsersPerAvailabilityAndStats(users []pagerduty.User, stats map[string]i
 
 	// sort per ranking
 	for i := 0; i < len(users); i++ {
-		j := maxIndex(rank)
+		j := maxIndex(rank, r)
 		sortedUsers = append(sortedUsers, users[j])
 		rank[j] = -1
 	}
@@ -145,19 +163,26 @@ func sortUsersPerAvailabilityAndStats(users []pagerduty.User, stats map[string]i
 	return sortedUsers
 }
 
-func sortUsers(d time.Time, users []pagerduty.User, stats map[string]int, method string) []pagerduty.User {
+// sortUsers ranks users for a shift with the given method, users preferring
+// the shift coming first.
+func sortUsers(sh shift, weekend pagerduty.Weekend, users []pagerduty.User, stats map[string]int, method string,
+	r *rand.Rand) []pagerduty.User {
+	var sorted []pagerduty.User
+
 	switch method {
 	case "PerAvailabilitySimple":
-		return sortUsersPerAvailabilitySimple(users)
+		sorted = sortUsersPerAvailabilitySimple(users, r)
 	case "PerRemainingAvailability":
-		return sortUsersPerRemainingAvailability(d, users)
+		sorted = sortUsersPerRemainingAvailability(sh, weekend, users, r)
 	case "PerAvailability":
-		return sortUsersPerAvailability(users)
+		sorted = sortUsersPerAvailability(users, r)
 	case "PerStats":
-		return sortUsersPerStats(users, stats)
+		sorted = sortUsersPerStats(users, stats, r)
 	case "PerAvailabilityAndStats":
-		return sortUsersPerAvailabilityAndStats(users, stats)
+		sorted = sortUsersPerAvailabilityAndStats(users, stats, r)
 	default:
-		return sortUsersPerAvailabilityAndStats(users, stats)
+		sorted = sortUsersPerAvailabilityAndStats(users, stats, r)
 	}
+
+	return preferredFirst(sh, sorted)
 }
@@ -1,22 +1,23 @@
 package utils
 
 import (
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/jtbonhomme/goshift/internal/pagerduty"
 )
 
-var location *time.Location
-
-func init() { //nolint:gochecknoinits // todo
-	var err error
-	location, err = time.LoadLocation("Europe/Paris")
-	if err != nil {
-		panic(err)
-	}
-}
+// Framadate answers, in French and English, every other answer meaning available.
+var (
+	unavailableAnswers = []string{"Non", "No"}
+	ifNeededAnswers    = []string{"Si nécessaire", "Ifneedbe", "If needed"}
+	preferredAnswers   = []string{"Préféré", "Preferred"}
+)
 
-func ParseFramadateCSV(data [][]string) pagerduty.Input {
+// ParseFramadateCSV reads availabilities and preferred days, shifts start at
+// the handover time of day in the schedule location.
+func ParseFramadateCSV(data [][]string, location *time.Location, handover time.Duration) pagerduty.Input {
 	var dates []time.Time
 	var input = pagerduty.Input{
 		ScheduleStart: time.Now().Add(10 * 365 * OneDay),
@@ -38,7 +39,7 @@ func ParseFramadateCSV(data [][]string) pagerduty.Input {
 				}
 
 				// TZ
-				t := time.Date(d.Year(), d.Month(), d.Day(), 9, 0, 0, 0, location)
+				t := AtTimeOfDay(d, handover, location)
 				if input.ScheduleStart.After(d) {
 					input.ScheduleStart = t
 				}
@@ -59,13 +60,19 @@ func ParseFramadateCSV(data [][]string) pagerduty.Input {
 
 		user := pagerduty.User{
 			Unavailable: []time.Time{},
+			IfNeeded:    []time.Time{},
 		}
 
 		for j, field := range line {
-			if j == 0 {
+			switch {
+			case j == 0:
 				user.Email = field
-			} else if field == "Non" {
+			case slices.Contains(unavailableAnswers, strings.TrimSpace(field)):
 				user.Unavailable = append(user.Unavailable, dates[j])
+			case slices.Contains(ifNeededAnswers, strings.TrimSpace(field)):
+				user.IfNeeded = append(user.IfNeeded, dates[j])
+			case slices.Contains(preferredAnswers, strings.TrimSpace(field)):
+				user.Preferred = append(user.Preferred, dates[j])
 			}
 		}
 
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+const (
+	icsDateFormat  string = "20060102"
+	yamlDateFormat string = "2006-01-02"
+)
+
+// ParseICS reads the dates of all-day events of an ICS calendar.
+func ParseICS(data []byte) ([]string, error) {
+	dates := []string{}
+	var start, end time.Time
+	var err error
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		switch {
+		case line == "BEGIN:VEVENT":
+			start, end = time.Time{}, time.Time{}
+		case strings.HasPrefix(name, "DTSTART"):
+			start, err = parseICSDate(value)
+		case strings.HasPrefix(name, "DTEND"):
+			end, err = parseICSDate(value)
+		case line == "END:VEVENT":
+			if start.IsZero() {
+				continue
+			}
+
+			// all-day events end is exclusive
+			if !end.After(start) {
+				end = start.Add(OneDay)
+			}
+			for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+				dates = append(dates, d.Format(yamlDateFormat))
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return dates, scanner.Err()
+}
+
+func parseICSDate(value string) (time.Time, error) {
+	if len(value) < len(icsDateFormat) {
+		return time.Time{}, fmt.Errorf("invalid ics date %s", value)
+	}
+
+	return time.Parse(icsDateFormat, value[:len(icsDateFormat)])
+}
+
+// ParseHolidaysYAML reads a simple YAML list of dates, optionally grouped per country:
+//
+//	FR:
+//	  - 2024-05-01
+//	  - 2024-05-08
+//
+// Dates belong to a country group when indented under it, dates out of a
+// country group belong to the given default country.
+func ParseHolidaysYAML(data []byte, country string) (pagerduty.Holidays, error) {
+	holidays := pagerduty.Holidays{}
+	current := country
+	// indentation of the current country group
+	groupIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		raw, _, _ := strings.Cut(scanner.Text(), "#")
+		line := strings.TrimSpace(raw)
+		indent := len(raw) - len(strings.TrimLeft(raw, " \t"))
+
+		switch {
+		case line == "" || line == "---":
+			continue
+		case strings.HasPrefix(line, "-"):
+			date := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-")), `"'`)
+			if _, err := time.Parse(yamlDateFormat, date); err != nil {
+				return nil, fmt.Errorf("invalid holiday date %s: %w", date, err)
+			}
+
+			group := country
+			if indent > groupIndent {
+				group = current
+			}
+			holidays.Add(group, date)
+		case strings.HasSuffix(line, ":"):
+			current = strings.TrimSuffix(line, ":")
+			groupIndent = indent
+		default:
+			return nil, fmt.Errorf("invalid holidays line %s", line)
+		}
+	}
+
+	return holidays, scanner.Err()
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseHolidaysYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want map[string][]string
+	}{
+		{
+			name: "dates without country",
+			data: "- 2024-05-01\n- \"2024-05-08\" # victory day\n",
+			want: map[string][]string{"FR": {"2024-05-01", "2024-05-08"}},
+		},
+		{
+			name: "country groups",
+			data: "FR:\n  - 2024-05-01\nUS:\n  - 2024-07-04\n",
+			want: map[string][]string{"FR": {"2024-05-01"}, "US": {"2024-07-04"}},
+		},
+		{
+			name: "dates out of a country group",
+			data: "- 2024-01-01\nUS:\n  - 2024-07-04\n- 2024-12-25\n",
+			want: map[string][]string{"FR": {"2024-01-01", "2024-12-25"}, "US": {"2024-07-04"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHolidaysYAML([]byte(tt.data), "FR")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for country, dates := range tt.want {
+				if !slices.Equal(got[country], dates) {
+					t.Errorf("%s: got %v, want %v", country, got[country], dates)
+				}
+			}
+		})
+	}
+}
+
+func TestParseHolidaysYAMLInvalidDate(t *testing.T) {
+	_, err := ParseHolidaysYAML([]byte("FR:\n  - 2024-13-01\n"), "")
+	if err == nil {
+		t.Error("invalid date accepted")
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"math"
+	"slices"
 )
 
 func Average(stats map[string]int) int {
@@ -26,10 +27,16 @@ func Max(stats map[string]int) int {
 	return max
 }
 
-func Min(stats map[string]int) int {
-	var min int
+// Number is an integer or a floating point value.
+type Number interface {
+	~int | ~float64
+}
+
+// Min returns the lowest value of stats, math.MaxInt when there is none so
+// that no value is above it.
+func Min[V Number](stats map[string]V) V {
+	min := V(math.MaxInt)
 
-	min = math.MaxInt
 	for _, val := range stats {
 		if val < min {
 			min = val
@@ -51,3 +58,30 @@ func MinWithoutZero(stats map[string]int) int {
 
 	return min
 }
+
+func Variance[V Number](stats map[string]V) float64 {
+	if len(stats) == 0 {
+		return 0
+	}
+
+	var cumul, squares float64
+
+	// sum in a stable order, map iteration order changing rounding errors
+	values := make([]float64, 0, len(stats))
+	for _, val := range stats {
+		values = append(values, float64(val))
+	}
+	slices.Sort(values)
+
+	for _, val := range values {
+		cumul += val
+	}
+
+	mean := cumul / float64(len(values))
+
+	for _, val := range values {
+		squares += (val - mean) * (val - mean)
+	}
+
+	return squares / float64(len(stats))
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMin(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats map[string]int
+		want  int
+	}{
+		{name: "values", stats: map[string]int{"a": 3, "b": 1, "c": 2}, want: 1},
+		{name: "zero value", stats: map[string]int{"a": 3, "b": 0}, want: 0},
+		{name: "negative value", stats: map[string]int{"a": -2, "b": 1}, want: -2},
+		{name: "no value", stats: map[string]int{}, want: math.MaxInt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Min(tt.stats); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinLoads(t *testing.T) {
+	if got := Min(map[string]float64{"a": 1.5, "b": 0.5}); got != 0.5 {
+		t.Errorf("got %v, want 0.5", got)
+	}
+
+	// no load is above the minimum of no load
+	if got := Min(map[string]float64{}); got < math.MaxInt {
+		t.Errorf("got %v, want at least %d", got, math.MaxInt)
+	}
+}
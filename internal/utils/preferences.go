@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+// preference lists the preferred dates (2006-01-02) and weekdays of a user.
+type preference struct {
+	Dates    []string `json:"dates,omitempty"`
+	Weekdays []string `json:"weekdays,omitempty"`
+}
+
+// ParsePreferences reads the preferred dates and weekdays of users, indexed by
+// email, preferred dates starting at the handover time of day in the schedule
+// location. Users must be in the availabilities.
+func ParsePreferences(data []byte, users []pagerduty.User, location *time.Location, handover time.Duration) error {
+	preferences := map[string]preference{}
+
+	err := json.Unmarshal(data, &preferences)
+	if err != nil {
+		return fmt.Errorf("unable to unmarshall preferences JSON value: %w", err)
+	}
+
+	for email := range preferences {
+		if !slices.ContainsFunc(users, func(u pagerduty.User) bool { return u.Email == email }) {
+			return fmt.Errorf("unknown user %s in preferences", email)
+		}
+	}
+
+	for i, user := range users {
+		p, ok := preferences[user.Email]
+		if !ok {
+			continue
+		}
+
+		for _, date := range p.Dates {
+			d, err := time.Parse(yamlDateFormat, date)
+			if err != nil {
+				return fmt.Errorf("invalid preferred date %s for user %s: %w", date, user.Email, err)
+			}
+			users[i].Preferred = append(users[i].Preferred, AtTimeOfDay(d, handover, location))
+		}
+
+		for _, day := range p.Weekdays {
+			weekday, err := pagerduty.ParseWeekday(day)
+			if err != nil {
+				return fmt.Errorf("invalid preferred weekday for user %s: %w", user.Email, err)
+			}
+			users[i].PreferredWeekdays = append(users[i].PreferredWeekdays, weekday)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jtbonhomme/goshift/internal/pagerduty"
+)
+
+func TestParseFramadateCSVPreferred(t *testing.T) {
+	data := [][]string{
+		{"", "01/09/2024", "02/09/2024", "03/09/2024"},
+		{"", "", "", ""},
+		{"alice@email.com", "Oui", "Préféré", "Non"},
+		{"bob@email.com", "Preferred", "Si nécessaire", "Oui"},
+	}
+
+	input := ParseFramadateCSV(data, time.UTC, 9*time.Hour)
+
+	want := map[string]string{
+		"alice@email.com": "2024-09-02",
+		"bob@email.com":   "2024-09-01",
+	}
+	for _, user := range input.Users {
+		if len(user.Preferred) != 1 || user.Preferred[0].Format(yamlDateFormat) != want[user.Email] {
+			t.Errorf("got %v preferred for %s, want %s", user.Preferred, user.Email, want[user.Email])
+		}
+	}
+}
+
+func TestParsePreferences(t *testing.T) {
+	users := []pagerduty.User{{Email: "alice@email.com"}, {Email: "bob@email.com"}}
+
+	err := ParsePreferences([]byte(`{"alice@email.com": {"dates": ["2024-09-14"], "weekdays": ["Monday"]}}`), users, time.UTC, 9*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(users[0].Preferred) != 1 || !users[0].Preferred[0].Equal(time.Date(2024, 9, 14, 9, 0, 0, 0, time.UTC)) {
+		t.Errorf("got %v preferred dates, want 2024-09-14 09:00", users[0].Preferred)
+	}
+	if len(users[0].PreferredWeekdays) != 1 || users[0].PreferredWeekdays[0] != time.Monday {
+		t.Errorf("got %v preferred weekdays, want Monday", users[0].PreferredWeekdays)
+	}
+	if len(users[1].Preferred) != 0 || len(users[1].PreferredWeekdays) != 0 {
+		t.Errorf("got preferences for bob, want none")
+	}
+}
+
+func TestParsePreferencesUnknownUser(t *testing.T) {
+	users := []pagerduty.User{{Email: "alice@email.com"}}
+
+	err := ParsePreferences([]byte(`{"carol@email.com": {"weekdays": ["Monday"]}}`), users, time.UTC, 9*time.Hour)
+	if err == nil {
+		t.Error("got no error for a user out of the availabilities")
+	}
+}
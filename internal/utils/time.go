@@ -8,3 +8,10 @@ const (
 	OneDay  time.Duration = time.Hour * 24
 	OneYear time.Duration = time.Hour * 24 * 365
 )
+
+// AtTimeOfDay returns the date of d at the given time of day (a duration since
+// midnight) in location, regardless of daylight saving time changes.
+func AtTimeOfDay(d time.Time, timeOfDay time.Duration, location *time.Location) time.Time {
+	return time.Date(d.Year(), d.Month(), d.Day(),
+		int(timeOfDay/time.Hour), int(timeOfDay%time.Hour/time.Minute), 0, 0, location)
+}